@@ -0,0 +1,76 @@
+package config
+
+import (
+	"singdns/api/ruleset"
+)
+
+// adBlockAllowlist 将白名单拆分为完整域名、域名后缀和关键字
+func adBlockAllowlist(allowlist []string) (domain, suffix, keyword []string) {
+	for _, value := range allowlist {
+		switch kind, value := parseDomainValue(value); kind {
+		case "domain":
+			domain = append(domain, value)
+		case "domain_suffix":
+			suffix = append(suffix, value)
+		case "domain_keyword":
+			keyword = append(keyword, value)
+		}
+	}
+	return domain, suffix, keyword
+}
+
+// buildAdBlockRouteRule 生成广告拦截路由规则，白名单中的域名不会被拦截
+func buildAdBlockRouteRule(allowlist []string) RouteRule {
+	domain, suffix, keyword := adBlockAllowlist(allowlist)
+	if len(domain) == 0 && len(suffix) == 0 && len(keyword) == 0 {
+		return RouteRule{
+			RuleSet:  []string{ruleset.AdBlockRuleSetTag},
+			Outbound: "block",
+		}
+	}
+
+	return RouteRule{
+		Type: "logical",
+		Mode: "and",
+		Rules: []RouteRule{
+			{
+				RuleSet: []string{ruleset.AdBlockRuleSetTag},
+			},
+			{
+				Domain:        domain,
+				DomainSuffix:  suffix,
+				DomainKeyword: keyword,
+				Invert:        true,
+			},
+		},
+		Outbound: "block",
+	}
+}
+
+// buildAdBlockDNSRule 生成广告拦截 DNS 规则，白名单中的域名不会被拦截
+func buildAdBlockDNSRule(allowlist []string) DNSRule {
+	domain, suffix, keyword := adBlockAllowlist(allowlist)
+	if len(domain) == 0 && len(suffix) == 0 && len(keyword) == 0 {
+		return DNSRule{
+			RuleSet: ruleset.AdBlockRuleSetTag,
+			Server:  dnsBlockTag,
+		}
+	}
+
+	return DNSRule{
+		Type: "logical",
+		Mode: "and",
+		Rules: []DNSRule{
+			{
+				RuleSet: ruleset.AdBlockRuleSetTag,
+			},
+			{
+				Domain:        domain,
+				DomainSuffix:  suffix,
+				DomainKeyword: keyword,
+				Invert:        true,
+			},
+		},
+		Server: dnsBlockTag,
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBinPath 相对于工作目录的 sing-box 可执行文件路径
+	DefaultBinPath = "bin/sing-box"
+	// checkTimeout sing-box check 的最长执行时间
+	checkTimeout = 30 * time.Second
+)
+
+// CheckError sing-box check 未通过时返回的错误，Output 为 sing-box 的输出
+type CheckError struct {
+	Output string
+}
+
+// Error implements error
+func (e *CheckError) Error() string {
+	return fmt.Sprintf("sing-box check failed: %s", e.Output)
+}
+
+// CheckConfigFile 使用 sing-box check 检查配置文件
+func CheckConfigFile(binPath, configPath string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, binPath, "check", "-c", configPath).CombinedOutput()
+	if ctx.Err() != nil {
+		return fmt.Errorf("sing-box check timed out after %s", checkTimeout)
+	}
+	if err != nil {
+		text := strings.TrimSpace(string(output))
+		if text == "" {
+			text = err.Error()
+		}
+		return &CheckError{Output: text}
+	}
+	return nil
+}
+
+// ApplyConfig 将配置写入候选文件，通过 sing-box check 后原子替换 configPath
+// 检查失败时保留原配置文件；binPath 不存在时 (如尚未下载 sing-box) 无法检查，
+// 直接替换配置文件并返回 checked 为 false
+func ApplyConfig(data []byte, configPath, binPath string) (checked bool, err error) {
+	dir := filepath.Dir(configPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, fmt.Errorf("create config directory: %w", err)
+	}
+
+	// 候选文件与配置文件放在同一目录，保证 rename 是原子操作
+	candidate, err := os.CreateTemp(dir, "config-*.json.candidate")
+	if err != nil {
+		return false, fmt.Errorf("create candidate config: %w", err)
+	}
+	candidatePath := candidate.Name()
+	defer os.Remove(candidatePath)
+
+	if _, err := candidate.Write(data); err != nil {
+		candidate.Close()
+		return false, fmt.Errorf("write candidate config: %w", err)
+	}
+	if err := candidate.Sync(); err != nil {
+		candidate.Close()
+		return false, fmt.Errorf("sync candidate config: %w", err)
+	}
+	if err := candidate.Close(); err != nil {
+		return false, fmt.Errorf("close candidate config: %w", err)
+	}
+	if err := os.Chmod(candidatePath, 0644); err != nil {
+		return false, fmt.Errorf("chmod candidate config: %w", err)
+	}
+
+	if _, err := os.Stat(binPath); err == nil {
+		if err := CheckConfigFile(binPath, candidatePath); err != nil {
+			// 错误信息中使用正式配置文件的路径
+			if checkErr, ok := err.(*CheckError); ok {
+				checkErr.Output = strings.ReplaceAll(checkErr.Output, candidatePath, configPath)
+			}
+			return false, err
+		}
+		checked = true
+	} else if !os.IsNotExist(err) {
+		return false, fmt.Errorf("stat sing-box binary: %w", err)
+	}
+
+	if err := os.Rename(candidatePath, configPath); err != nil {
+		return false, fmt.Errorf("replace config file: %w", err)
+	}
+	return checked, nil
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	passBin := filepath.Join(dir, "sing-box-pass")
+	failBin := filepath.Join(dir, "sing-box-fail")
+	if err := os.WriteFile(passBin, []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(failBin, []byte("#!/bin/sh\necho \"decode config at $3: bad\"\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		data        string
+		binPath     string
+		wantChecked bool
+		wantErr     bool
+		wantContent string
+	}{
+		{name: "binary missing", data: `{"a":1}`, binPath: filepath.Join(dir, "missing"), wantContent: `{"a":1}`},
+		{name: "check passed", data: `{"a":2}`, binPath: passBin, wantChecked: true, wantContent: `{"a":2}`},
+		// 检查失败时保留上一次的配置
+		{name: "check failed", data: `{"a":3}`, binPath: failBin, wantErr: true, wantContent: `{"a":2}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checked, err := ApplyConfig([]byte(tt.data), configPath, tt.binPath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if checked != tt.wantChecked {
+				t.Errorf("ApplyConfig() checked = %v, want %v", checked, tt.wantChecked)
+			}
+			content, err := os.ReadFile(configPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.wantContent {
+				t.Errorf("config content = %s, want %s", content, tt.wantContent)
+			}
+		})
+	}
+}
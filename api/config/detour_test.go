@@ -0,0 +1,78 @@
+package config
+
+import (
+	"io"
+	"testing"
+
+	"singdns/api/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestGenerator() *SingBoxGenerator {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return &SingBoxGenerator{logger: logger}
+}
+
+func TestResolveDetoursSkipsInvalidTargets(t *testing.T) {
+	g := newTestGenerator()
+	nodes := []models.Node{{ID: "jp", Name: "JP"}}
+	groups := []models.NodeGroup{
+		{ID: "empty", Name: "空组", Active: true},
+		// 成员节点都被跳过，未生成节点组出站
+		{ID: "skipped", Name: "失效组", Active: true, Nodes: []models.Node{{ID: "bad", Name: "Bad"}}},
+	}
+	outbounds := []OutboundConfig{
+		{Type: "trojan", Tag: "A", Detour: "deleted-node-id"},
+		{Type: "trojan", Tag: "B", Detour: "空组"},
+		{Type: "trojan", Tag: "C", Detour: "jp"},
+		{Type: "trojan", Tag: "D", Detour: "失效组"},
+	}
+	nodeTags := map[string]string{"jp": "JP [jp]"}
+	usedTags := map[string]bool{"A": true, "B": true, "C": true, "D": true, "JP [jp]": true}
+
+	outbounds, err := g.resolveDetours(outbounds, nodes, groups, nodeTags, usedTags)
+	if err != nil {
+		t.Fatalf("resolveDetours() error = %v", err)
+	}
+	want := []string{"", "", "JP [jp]", ""}
+	for i, detour := range want {
+		if outbounds[i].Detour != detour {
+			t.Errorf("outbound %s detour = %q, want %q", outbounds[i].Tag, outbounds[i].Detour, detour)
+		}
+	}
+}
+
+func TestDropDetourCycles(t *testing.T) {
+	g := newTestGenerator()
+	outbounds := []OutboundConfig{
+		{Type: "trojan", Tag: "A", Detour: "中转"},
+		{Type: "trojan", Tag: "B", Detour: "A"},
+		{Type: "selector", Tag: "中转", Outbounds: []string{"B"}},
+		{Type: "trojan", Tag: "C", Detour: "B"},
+		{Type: "trojan", Tag: "Self", Detour: "Self"},
+	}
+	g.dropDetourCycles(outbounds)
+
+	index := make(map[string]int)
+	for i := range outbounds {
+		index[outbounds[i].Tag] = i
+	}
+	if cycle := findDetourCycle(outbounds, index); cycle != nil {
+		t.Fatalf("cycle remains: %v", cycle)
+	}
+	if outbounds[4].Detour != "" {
+		t.Errorf("self detour not dropped")
+	}
+	// 只删除一条代理链即可打破 A -> 中转 -> B -> A
+	if outbounds[0].Detour != "" && outbounds[1].Detour != "" {
+		t.Errorf("cycle A -> 中转 -> B -> A not broken")
+	}
+	if outbounds[0].Detour == "" && outbounds[1].Detour == "" {
+		t.Errorf("both detours dropped, want only one")
+	}
+	if outbounds[3].Detour != "B" {
+		t.Errorf("detour outside cycle dropped")
+	}
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tagMatchedArrays 按元素 tag 匹配的数组路径，插入或删除一项时不影响其余元素的比较
+var tagMatchedArrays = map[string]bool{
+	"/outbounds":   true,
+	"/inbounds":    true,
+	"/dns/servers": true,
+}
+
+// DiffEntry 两个 JSON 文档之间的一处差异，Path 为 JSON Pointer
+type DiffEntry struct {
+	Op   string      `json:"op"` // add, remove 或 replace
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// DiffJSON 比较两个 JSON 文档，数组按下标逐项比较，出站、入站和 DNS 服务器按 tag 匹配
+func DiffJSON(from, to []byte) ([]DiffEntry, error) {
+	var a, b interface{}
+	if err := json.Unmarshal(from, &a); err != nil {
+		return nil, fmt.Errorf("parse old config: %w", err)
+	}
+	if err := json.Unmarshal(to, &b); err != nil {
+		return nil, fmt.Errorf("parse new config: %w", err)
+	}
+	diff := []DiffEntry{}
+	diffValue("", a, b, &diff)
+	return diff, nil
+}
+
+// diffValue 递归比较 JSON 值并记录差异
+func diffValue(path string, a, b interface{}, diff *[]DiffEntry) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for key := range av {
+			keys = append(keys, key)
+		}
+		for key := range bv {
+			if _, ok := av[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := path + "/" + escapePointer(key)
+			oldValue, inA := av[key]
+			newValue, inB := bv[key]
+			switch {
+			case !inA:
+				*diff = append(*diff, DiffEntry{Op: "add", Path: child, New: newValue})
+			case !inB:
+				*diff = append(*diff, DiffEntry{Op: "remove", Path: child, Old: oldValue})
+			default:
+				diffValue(child, oldValue, newValue, diff)
+			}
+		}
+		return
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		if tagMatchedArrays[path] {
+			oldTags, oldOK := arrayTags(av)
+			newTags, newOK := arrayTags(bv)
+			if oldOK && newOK {
+				diffTaggedArray(path, av, bv, oldTags, newTags, diff)
+				return
+			}
+		}
+		for i := 0; i < len(av) || i < len(bv); i++ {
+			child := path + "/" + strconv.Itoa(i)
+			switch {
+			case i >= len(av):
+				*diff = append(*diff, DiffEntry{Op: "add", Path: child, New: bv[i]})
+			case i >= len(bv):
+				*diff = append(*diff, DiffEntry{Op: "remove", Path: child, Old: av[i]})
+			default:
+				diffValue(child, av[i], bv[i], diff)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*diff = append(*diff, DiffEntry{Op: "replace", Path: path, Old: a, New: b})
+	}
+}
+
+// arrayTags 返回数组元素的 tag 到下标的映射，有元素缺少 tag 或 tag 重复时返回 false
+func arrayTags(items []interface{}) (map[string]int, bool) {
+	tags := make(map[string]int, len(items))
+	for i, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		tag, ok := object["tag"].(string)
+		if !ok || tag == "" {
+			return nil, false
+		}
+		if _, exists := tags[tag]; exists {
+			return nil, false
+		}
+		tags[tag] = i
+	}
+	return tags, true
+}
+
+// diffTaggedArray 按 tag 比较数组元素，删除的元素使用旧数组下标，其余使用新数组下标
+// 只调整顺序的元素不记为差异
+func diffTaggedArray(path string, a, b []interface{}, oldTags, newTags map[string]int, diff *[]DiffEntry) {
+	for i, item := range a {
+		tag := item.(map[string]interface{})["tag"].(string)
+		if _, ok := newTags[tag]; !ok {
+			*diff = append(*diff, DiffEntry{Op: "remove", Path: path + "/" + strconv.Itoa(i), Old: item})
+		}
+	}
+	for i, item := range b {
+		child := path + "/" + strconv.Itoa(i)
+		tag := item.(map[string]interface{})["tag"].(string)
+		if j, ok := oldTags[tag]; ok {
+			diffValue(child, a[j], item, diff)
+		} else {
+			*diff = append(*diff, DiffEntry{Op: "add", Path: child, New: item})
+		}
+	}
+}
+
+// escapePointer 按 RFC 6901 转义 JSON Pointer 中的键名
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []DiffEntry
+	}{
+		{name: "equal", from: `{"a":1,"b":[1,2]}`, to: `{"b":[1,2],"a":1}`, want: []DiffEntry{}},
+		{
+			name: "object changes",
+			from: `{"log":{"level":"info"},"old":true}`,
+			to:   `{"log":{"level":"debug"},"new":"x"}`,
+			want: []DiffEntry{
+				{Op: "replace", Path: "/log/level", Old: "info", New: "debug"},
+				{Op: "add", Path: "/new", New: "x"},
+				{Op: "remove", Path: "/old", Old: true},
+			},
+		},
+		{
+			name: "array by index",
+			from: `{"rules":[1,2,3]}`,
+			to:   `{"rules":[1,4]}`,
+			want: []DiffEntry{
+				{Op: "replace", Path: "/rules/1", Old: float64(2), New: float64(4)},
+				{Op: "remove", Path: "/rules/2", Old: float64(3)},
+			},
+		},
+		{
+			name: "type change",
+			from: `{"a":{"b":1}}`,
+			to:   `{"a":[1]}`,
+			want: []DiffEntry{
+				{Op: "replace", Path: "/a", Old: map[string]interface{}{"b": float64(1)}, New: []interface{}{float64(1)}},
+			},
+		},
+		{
+			name: "outbounds by tag",
+			from: `{"outbounds":[{"tag":"direct-out","type":"direct"},{"tag":"A","server":"a.com"},{"tag":"B","server":"b.com"}]}`,
+			to:   `{"outbounds":[{"tag":"new","type":"vmess"},{"tag":"direct-out","type":"direct"},{"tag":"B","server":"b.net"}]}`,
+			want: []DiffEntry{
+				{Op: "remove", Path: "/outbounds/1", Old: map[string]interface{}{"tag": "A", "server": "a.com"}},
+				{Op: "add", Path: "/outbounds/0", New: map[string]interface{}{"tag": "new", "type": "vmess"}},
+				{Op: "replace", Path: "/outbounds/2/server", Old: "b.com", New: "b.net"},
+			},
+		},
+		{
+			name: "dns servers reordered",
+			from: `{"dns":{"servers":[{"tag":"a","address":"1.1.1.1"},{"tag":"b","address":"8.8.8.8"}]}}`,
+			to:   `{"dns":{"servers":[{"tag":"b","address":"8.8.8.8"},{"tag":"a","address":"1.1.1.1"}]}}`,
+			want: []DiffEntry{},
+		},
+		{
+			name: "inbounds without tags by index",
+			from: `{"inbounds":[{"type":"tun"},{"tag":"dns-in","type":"direct"}]}`,
+			to:   `{"inbounds":[{"tag":"dns-in","type":"direct"}]}`,
+			want: []DiffEntry{
+				{Op: "add", Path: "/inbounds/0/tag", New: "dns-in"},
+				{Op: "replace", Path: "/inbounds/0/type", Old: "tun", New: "direct"},
+				{Op: "remove", Path: "/inbounds/1", Old: map[string]interface{}{"tag": "dns-in", "type": "direct"}},
+			},
+		},
+		{
+			name: "duplicate tags by index",
+			from: `{"outbounds":[{"tag":"A","port":1},{"tag":"A","port":2}]}`,
+			to:   `{"outbounds":[{"tag":"A","port":2}]}`,
+			want: []DiffEntry{
+				{Op: "replace", Path: "/outbounds/0/port", Old: float64(1), New: float64(2)},
+				{Op: "remove", Path: "/outbounds/1", Old: map[string]interface{}{"tag": "A", "port": float64(2)}},
+			},
+		},
+		{
+			name: "other arrays with tags by index",
+			from: `{"route":{"rule_set":[{"tag":"a"},{"tag":"b"}]}}`,
+			to:   `{"route":{"rule_set":[{"tag":"b"}]}}`,
+			want: []DiffEntry{
+				{Op: "replace", Path: "/route/rule_set/0/tag", Old: "a", New: "b"},
+				{Op: "remove", Path: "/route/rule_set/1", Old: map[string]interface{}{"tag": "b"}},
+			},
+		},
+		{
+			name: "escaped keys",
+			from: `{"a/b":1,"c~d":1}`,
+			to:   `{"a/b":2,"c~d":1}`,
+			want: []DiffEntry{{Op: "replace", Path: "/a~1b", Old: float64(1), New: float64(2)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiffJSON([]byte(tt.from), []byte(tt.to))
+			if err != nil {
+				t.Fatalf("DiffJSON() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffJSON() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffJSONInvalid(t *testing.T) {
+	if _, err := DiffJSON([]byte(`{`), []byte(`{}`)); err == nil {
+		t.Error("DiffJSON() with invalid old config should fail")
+	}
+	if _, err := DiffJSON([]byte(`{}`), []byte(`[`)); err == nil {
+		t.Error("DiffJSON() with invalid new config should fail")
+	}
+}
@@ -0,0 +1,397 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"singdns/api/models"
+)
+
+const (
+	// DNS 服务器标签
+	dnsDomesticTag = "alidns"
+	dnsRemoteTag   = "google"
+	dnsBlockTag    = "dns-block"
+	dnsLocalTag    = "dns-local"
+	dnsFakeIPTag   = "fakeip"
+)
+
+// generateDNSConfig 根据 DNS 设置生成 DNS 配置
+func (g *SingBoxGenerator) generateDNSConfig(dnsSettings *models.DNSSettings, outbounds []OutboundConfig, aliases map[string]string) (*DNSConfig, error) {
+	// 补全默认值
+	if err := dnsSettings.Validate(); err != nil {
+		return nil, fmt.Errorf("validate dns settings: %w", err)
+	}
+
+	domesticServer := DNSServerConfig{
+		Tag:             dnsDomesticTag,
+		Address:         buildDNSAddress(dnsSettings.Domestic, dnsSettings.DomesticType),
+		AddressStrategy: "prefer_ipv4",
+		Detour:          "direct-out",
+	}
+	remoteServer := DNSServerConfig{
+		Tag:             dnsRemoteTag,
+		Address:         buildDNSAddress(dnsSettings.SingboxDNS, dnsSettings.SingboxDNSType),
+		AddressResolver: dnsDomesticTag,
+	}
+
+	servers := []DNSServerConfig{domesticServer, remoteServer}
+
+	// 国内 DNS 使用域名时需要通过系统 DNS 解析
+	if needsAddressResolver(domesticServer.Address) {
+		servers[0].AddressResolver = dnsLocalTag
+		servers = append(servers, DNSServerConfig{
+			Tag:     dnsLocalTag,
+			Address: "local",
+			Detour:  "direct-out",
+		})
+	}
+
+	servers = append(servers, DNSServerConfig{
+		Tag:     dnsBlockTag,
+		Address: "rcode://refused",
+	})
+
+	if dnsSettings.EnableFakeIP {
+		servers = append(servers, DNSServerConfig{
+			Tag:     dnsFakeIPTag,
+			Address: "fakeip",
+		})
+	}
+
+	// 用户自定义 DNS 服务器
+	customServers, err := g.generateCustomDNSServers(outbounds, aliases)
+	if err != nil {
+		return nil, err
+	}
+	servers = append(servers, customServers...)
+
+	serverTags := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		serverTags[server.Tag] = true
+	}
+
+	// 生成用户自定义 DNS 规则
+	userDNSRules, err := g.generateUserDNSRules(serverTags)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []DNSRule{
+		{
+			Outbound: "any",
+			Server:   dnsDomesticTag,
+		},
+		{
+			ClashMode: "direct",
+			Server:    dnsDomesticTag,
+		},
+		{
+			ClashMode: "global",
+			Server:    dnsRemoteTag,
+		},
+	}
+	rules = append(rules, userDNSRules...)
+	if dnsSettings.EnableAdBlock {
+		rules = append(rules, buildAdBlockDNSRule(dnsSettings.AdBlockAllowlist))
+	}
+	if dnsSettings.EnableFakeIP {
+		// 过滤列表中的域名使用真实地址
+		if filterRule, ok := buildFakeIPFilterRule(dnsSettings.FakeIPFilter); ok {
+			rules = append(rules, filterRule)
+		}
+	}
+	rules = append(rules,
+		DNSRule{
+			RuleSet: "geosite-cn",
+			Server:  dnsDomesticTag,
+		},
+	)
+	if dnsSettings.EnableFakeIP {
+		// 其余需要代理的域名返回 FakeIP，这些 A/AAAA 查询不经过上游 DNS，不使用 ECS
+		rules = append(rules, DNSRule{
+			QueryType: []string{"A", "AAAA"},
+			Server:    dnsFakeIPTag,
+		})
+	}
+	rules = append(rules,
+		DNSRule{
+			Type: "logical",
+			Mode: "and",
+			Rules: []DNSRule{
+				{
+					RuleSet: "geosite-geolocation-!cn",
+					Invert:  true,
+				},
+				{
+					RuleSet: "geoip-cn",
+				},
+			},
+			Server:       dnsRemoteTag,
+			ClientSubnet: dnsSettings.EDNSClientSubnet,
+		},
+	)
+
+	if dnsSettings.EnableFakeIP && dnsSettings.EDNSClientSubnet != "" {
+		g.logger.Warn("EDNS client subnet is not used for A/AAAA queries answered by fakeip")
+	}
+
+	final, ok := resolveDNSFinal(dnsSettings.Final, serverTags)
+	if !ok {
+		g.logger.Warnf("DNS final server %s is not available, use %s", dnsSettings.Final, final)
+	}
+
+	var fakeIP *DNSFakeIPConfig
+	if dnsSettings.EnableFakeIP {
+		fakeIP = &DNSFakeIPConfig{
+			Enabled:    true,
+			Inet4Range: dnsSettings.FakeIPInet4Range,
+		}
+		// 仅使用 IPv4 时不分配 IPv6 FakeIP
+		if dnsSettings.Strategy != "ipv4_only" {
+			fakeIP.Inet6Range = dnsSettings.FakeIPInet6Range
+		}
+	}
+
+	return &DNSConfig{
+		FakeIP:           fakeIP,
+		Servers:          servers,
+		Rules:            rules,
+		Final:            final,
+		Strategy:         dnsSettings.Strategy,
+		DisableCache:     dnsSettings.DisableCache,
+		DisableExpire:    dnsSettings.DisableExpire,
+		IndependentCache: dnsSettings.IndependentCache,
+		ReverseMapping:   dnsSettings.ReverseMapping,
+	}, nil
+}
+
+// generateCustomDNSServers 将数据库中的 DNS 服务器转换为 DNS 服务器配置
+func (g *SingBoxGenerator) generateCustomDNSServers(outbounds []OutboundConfig, aliases map[string]string) ([]DNSServerConfig, error) {
+	dbServers, err := g.storage.GetDNSServers()
+	if err != nil {
+		return nil, fmt.Errorf("get dns servers: %w", err)
+	}
+
+	outboundTags := outboundTagSet(outbounds)
+
+	// 先收集所有启用的服务器标签，用于校验解析服务器
+	enabledTags := map[string]bool{
+		dnsDomesticTag: true,
+		dnsRemoteTag:   true,
+	}
+	for _, dbServer := range dbServers {
+		if dbServer.Enabled {
+			enabledTags[dbServer.Tag] = true
+		}
+	}
+
+	var servers []DNSServerConfig
+	for _, dbServer := range dbServers {
+		if !dbServer.Enabled {
+			continue
+		}
+
+		server := DNSServerConfig{
+			Tag:          dbServer.Tag,
+			Address:      buildDNSAddress(dbServer.Address, dbServer.Type),
+			Strategy:     dbServer.Strategy,
+			ClientSubnet: dbServer.ClientSubnet,
+		}
+
+		// 出站可能已随订阅更新或节点组停用而不存在，此时不使用代理链
+		if dbServer.Detour != "" {
+			if detour, ok := resolveOutboundTag(dbServer.Detour, outboundTags, aliases); ok {
+				server.Detour = detour
+			} else {
+				g.logger.Warnf("DNS server %s: unknown detour %s, skip detour", dbServer.Tag, dbServer.Detour)
+			}
+		}
+
+		// 服务器地址为域名时需要指定解析服务器，默认使用国内 DNS
+		if needsAddressResolver(server.Address) {
+			server.AddressResolver = dnsDomesticTag
+			if dbServer.AddressResolver != "" {
+				if enabledTags[dbServer.AddressResolver] {
+					server.AddressResolver = dbServer.AddressResolver
+				} else {
+					g.logger.Warnf("DNS server %s: unknown address resolver %s, use %s", dbServer.Tag, dbServer.AddressResolver, dnsDomesticTag)
+				}
+			}
+		}
+
+		servers = append(servers, server)
+	}
+
+	return servers, nil
+}
+
+// generateUserDNSRules 将数据库中的 DNS 规则转换为 DNS 配置规则
+func (g *SingBoxGenerator) generateUserDNSRules(serverTags map[string]bool) ([]DNSRule, error) {
+	dbRules, err := g.storage.GetDNSRules()
+	if err != nil {
+		return nil, fmt.Errorf("get dns rules: %w", err)
+	}
+
+	var rules []DNSRule
+	for _, dbRule := range dbRules {
+		if !dbRule.Enabled {
+			continue
+		}
+
+		var rule DNSRule
+		switch dbRule.Action {
+		case "direct":
+			rule.Server = dnsDomesticTag
+		case "remote":
+			rule.Server = dnsRemoteTag
+		case "block":
+			rule.Server = dnsBlockTag
+		default:
+			// 引用的 DNS 服务器不存在时跳过
+			if !serverTags[dbRule.Action] {
+				continue
+			}
+			rule.Server = dbRule.Action
+		}
+
+		switch dbRule.Type {
+		case "domain":
+			switch kind, domain := parseDomainValue(dbRule.Value); kind {
+			case "domain":
+				rule.Domain = []string{domain}
+			case "domain_keyword":
+				rule.DomainKeyword = []string{domain}
+			case "domain_suffix":
+				rule.DomainSuffix = []string{domain}
+			default:
+				continue
+			}
+		case "ip":
+			cidr := parseIPValue(dbRule.Value)
+			if cidr == "" {
+				continue
+			}
+			rule.IPCIDR = []string{cidr}
+		default:
+			continue
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// buildFakeIPFilterRule 生成 FakeIP 过滤规则，命中的域名交给国内 DNS 解析
+func buildFakeIPFilterRule(filter []string) (DNSRule, bool) {
+	rule := DNSRule{Server: dnsDomesticTag}
+	for _, value := range filter {
+		switch kind, domain := parseDomainValue(value); kind {
+		case "domain":
+			rule.Domain = append(rule.Domain, domain)
+		case "domain_suffix":
+			rule.DomainSuffix = append(rule.DomainSuffix, domain)
+		case "domain_keyword":
+			rule.DomainKeyword = append(rule.DomainKeyword, domain)
+		}
+	}
+	if len(rule.Domain) == 0 && len(rule.DomainSuffix) == 0 && len(rule.DomainKeyword) == 0 {
+		return rule, false
+	}
+	return rule, true
+}
+
+// resolveDNSFinal 将 DNS 设置中的 Final 转换为 DNS 服务器标签
+// 服务器不存在或不能作为最终服务器时使用远程 DNS 并返回 false
+func resolveDNSFinal(final string, serverTags map[string]bool) (string, bool) {
+	switch final {
+	case dnsDomesticTag, "domestic", "direct":
+		return dnsDomesticTag, true
+	case dnsRemoteTag, "remote":
+		return dnsRemoteTag, true
+	case dnsBlockTag, dnsLocalTag:
+		// 拦截服务器会拒绝所有未匹配的查询，系统 DNS 只用于解析国内 DNS 的地址
+		return dnsRemoteTag, false
+	}
+	if serverTags[final] {
+		return final, true
+	}
+	return dnsRemoteTag, false
+}
+
+// buildDNSAddress 根据 DNS 类型生成 sing-box 的服务器地址
+// 支持 udp、tcp、tls(dot)、https(doh)、quic(doq) 和 h3(doh3)
+func buildDNSAddress(address, dnsType string) string {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return address
+	}
+
+	// 特殊地址保持不变
+	if address == "local" || strings.HasPrefix(address, "rcode://") || strings.HasPrefix(address, "dhcp://") || address == "fakeip" {
+		return address
+	}
+
+	// 拆分已有的协议头和路径
+	host := address
+	path := ""
+	scheme := ""
+	if idx := strings.Index(address, "://"); idx >= 0 {
+		scheme = address[:idx]
+		host = address[idx+3:]
+	}
+	if idx := strings.Index(host, "/"); idx >= 0 {
+		path = host[idx:]
+		host = host[:idx]
+	}
+
+	switch strings.ToLower(dnsType) {
+	case "udp":
+		scheme = "udp"
+	case "tcp":
+		scheme = "tcp"
+	case "tls", "dot":
+		scheme = "tls"
+	case "https", "doh":
+		scheme = "https"
+	case "quic", "doq":
+		scheme = "quic"
+	case "h3", "doh3":
+		scheme = "h3"
+	}
+
+	switch scheme {
+	case "":
+		// 未指定类型时直接使用原始地址
+		return address
+	case "udp":
+		// sing-box 默认使用 UDP
+		return host
+	case "https", "h3":
+		if path == "" {
+			path = "/dns-query"
+		}
+		return fmt.Sprintf("%s://%s%s", scheme, host, path)
+	default:
+		return fmt.Sprintf("%s://%s", scheme, host)
+	}
+}
+
+// needsAddressResolver 判断 DNS 服务器地址是否为域名
+func needsAddressResolver(address string) bool {
+	if address == "" || address == "local" || strings.Contains(address, "rcode://") {
+		return false
+	}
+	host := address
+	if !strings.Contains(host, "://") {
+		host = "udp://" + host
+	}
+	u, err := url.Parse(host)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	return net.ParseIP(u.Hostname()) == nil
+}
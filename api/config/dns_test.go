@@ -0,0 +1,111 @@
+package config
+
+import (
+	"testing"
+
+	"singdns/api/models"
+	"singdns/api/storage"
+)
+
+// fakeStorage 只实现测试用到的存储方法
+type fakeStorage struct {
+	storage.Storage
+	dnsServers []models.DNSServer
+	rules      []models.Rule
+}
+
+func (f *fakeStorage) GetDNSServers() ([]models.DNSServer, error) {
+	return f.dnsServers, nil
+}
+
+func (f *fakeStorage) GetRules() ([]models.Rule, error) {
+	return f.rules, nil
+}
+
+func TestResolveDNSFinal(t *testing.T) {
+	serverTags := map[string]bool{
+		dnsDomesticTag: true,
+		dnsRemoteTag:   true,
+		dnsBlockTag:    true,
+		dnsLocalTag:    true,
+		"custom":       true,
+	}
+	tests := []struct {
+		final  string
+		want   string
+		wantOK bool
+	}{
+		{final: "direct", want: dnsDomesticTag, wantOK: true},
+		{final: "domestic", want: dnsDomesticTag, wantOK: true},
+		{final: "remote", want: dnsRemoteTag, wantOK: true},
+		{final: "google", want: dnsRemoteTag, wantOK: true},
+		{final: "custom", want: "custom", wantOK: true},
+		{final: dnsBlockTag, want: dnsRemoteTag},
+		{final: dnsLocalTag, want: dnsRemoteTag},
+		// FakeIP 未启用时没有 fakeip 服务器
+		{final: dnsFakeIPTag, want: dnsRemoteTag},
+		{final: "deleted", want: dnsRemoteTag},
+	}
+	for _, tt := range tests {
+		got, ok := resolveDNSFinal(tt.final, serverTags)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("resolveDNSFinal(%q) = %q, %v, want %q, %v", tt.final, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestGenerateCustomDNSServersSkipsUnknownReferences(t *testing.T) {
+	g := newTestGenerator()
+	g.storage = &fakeStorage{dnsServers: []models.DNSServer{
+		{Tag: "proxied", Address: "dns.example.com", Type: "https", Detour: "香港", Enabled: true},
+		{Tag: "stale", Address: "dns.example.org", Type: "tls", Detour: "deleted-group", AddressResolver: "deleted", Enabled: true},
+		{Tag: "disabled", Address: "1.1.1.1", Detour: "deleted-group"},
+	}}
+	outbounds := []OutboundConfig{{Type: "selector", Tag: "香港 🇭🇰"}}
+	aliases := map[string]string{"香港": "香港 🇭🇰"}
+
+	servers, err := g.generateCustomDNSServers(outbounds, aliases)
+	if err != nil {
+		t.Fatalf("generateCustomDNSServers() error = %v", err)
+	}
+	if len(servers) != 2 {
+		t.Fatalf("got %d servers, want 2", len(servers))
+	}
+	if servers[0].Detour != "香港 🇭🇰" {
+		t.Errorf("proxied detour = %q, want %q", servers[0].Detour, "香港 🇭🇰")
+	}
+	if servers[1].Detour != "" || servers[1].AddressResolver != dnsDomesticTag {
+		t.Errorf("stale detour = %q, address resolver = %q, want no detour and %q", servers[1].Detour, servers[1].AddressResolver, dnsDomesticTag)
+	}
+}
+
+func TestBuildDNSAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		dnsType string
+		want    string
+	}{
+		{address: "", dnsType: "udp", want: ""},
+		{address: "223.5.5.5", dnsType: "udp", want: "223.5.5.5"},
+		{address: "udp://223.5.5.5", dnsType: "", want: "223.5.5.5"},
+		{address: " 223.5.5.5 ", dnsType: "tcp", want: "tcp://223.5.5.5"},
+		{address: "dns.alidns.com", dnsType: "dot", want: "tls://dns.alidns.com"},
+		{address: "dns.google", dnsType: "doh", want: "https://dns.google/dns-query"},
+		{address: "https://dns.google/resolve", dnsType: "doh", want: "https://dns.google/resolve"},
+		{address: "https://dns.google/dns-query", dnsType: "tls", want: "tls://dns.google"},
+		{address: "dns.adguard.com", dnsType: "doq", want: "quic://dns.adguard.com"},
+		{address: "dns.google", dnsType: "doh3", want: "h3://dns.google/dns-query"},
+		{address: "tls://1.1.1.1", dnsType: "", want: "tls://1.1.1.1"},
+		{address: "8.8.8.8", dnsType: "", want: "8.8.8.8"},
+		// 特殊地址保持不变
+		{address: "local", dnsType: "https", want: "local"},
+		{address: "rcode://refused", dnsType: "udp", want: "rcode://refused"},
+		{address: "dhcp://auto", dnsType: "tls", want: "dhcp://auto"},
+		{address: "fakeip", dnsType: "udp", want: "fakeip"},
+	}
+	for _, tt := range tests {
+		if got := buildDNSAddress(tt.address, tt.dnsType); got != tt.want {
+			t.Errorf("buildDNSAddress(%q, %q) = %q, want %q", tt.address, tt.dnsType, got, tt.want)
+		}
+	}
+}
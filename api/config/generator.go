@@ -4,7 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"singdns/api/models"
+	"singdns/api/ruleset"
 	"singdns/api/storage"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
 )
 
 // ConfigGenerator 配置生成器接口
@@ -41,8 +48,9 @@ type ClashAPIConfig struct {
 
 // CacheFileConfig 缓存文件配置
 type CacheFileConfig struct {
-	Enabled bool   `json:"enabled"`
-	Path    string `json:"path"`
+	Enabled     bool   `json:"enabled"`
+	Path        string `json:"path"`
+	StoreFakeIP bool   `json:"store_fakeip,omitempty"`
 }
 
 // DNSConfig DNS配置
@@ -54,6 +62,15 @@ type DNSConfig struct {
 	DisableCache     bool              `json:"disable_cache,omitempty"`
 	DisableExpire    bool              `json:"disable_expire,omitempty"`
 	IndependentCache bool              `json:"independent_cache,omitempty"`
+	ReverseMapping   bool              `json:"reverse_mapping,omitempty"`
+	FakeIP           *DNSFakeIPConfig  `json:"fakeip,omitempty"`
+}
+
+// DNSFakeIPConfig FakeIP 配置
+type DNSFakeIPConfig struct {
+	Enabled    bool   `json:"enabled"`
+	Inet4Range string `json:"inet4_range,omitempty"`
+	Inet6Range string `json:"inet6_range,omitempty"`
 }
 
 // DNSServerConfig DNS服务器配置
@@ -69,17 +86,21 @@ type DNSServerConfig struct {
 
 // DNSRule DNS规则配置
 type DNSRule struct {
-	RuleSet      string    `json:"rule_set,omitempty"`
-	Domain       []string  `json:"domain,omitempty"`
-	Server       string    `json:"server,omitempty"`
-	Outbound     string    `json:"outbound,omitempty"`
-	DisableCache bool      `json:"disable_cache,omitempty"`
-	Type         string    `json:"type,omitempty"`
-	Mode         string    `json:"mode,omitempty"`
-	Rules        []DNSRule `json:"rules,omitempty"`
-	Invert       bool      `json:"invert,omitempty"`
-	ClashMode    string    `json:"clash_mode,omitempty"`
-	ClientSubnet string    `json:"client_subnet,omitempty"`
+	RuleSet       string    `json:"rule_set,omitempty"`
+	Domain        []string  `json:"domain,omitempty"`
+	DomainSuffix  []string  `json:"domain_suffix,omitempty"`
+	DomainKeyword []string  `json:"domain_keyword,omitempty"`
+	IPCIDR        []string  `json:"ip_cidr,omitempty"`
+	QueryType     []string  `json:"query_type,omitempty"`
+	Server        string    `json:"server,omitempty"`
+	Outbound      string    `json:"outbound,omitempty"`
+	DisableCache  bool      `json:"disable_cache,omitempty"`
+	Type          string    `json:"type,omitempty"`
+	Mode          string    `json:"mode,omitempty"`
+	Rules         []DNSRule `json:"rules,omitempty"`
+	Invert        bool      `json:"invert,omitempty"`
+	ClashMode     string    `json:"clash_mode,omitempty"`
+	ClientSubnet  string    `json:"client_subnet,omitempty"`
 }
 
 // EDNSConfig EDNS配置
@@ -95,6 +116,7 @@ type InboundConfig struct {
 	Listen_Port              int    `json:"listen_port,omitempty"`
 	InterfaceName            string `json:"interface_name,omitempty"`
 	Inet4Address             string `json:"inet4_address,omitempty"`
+	Inet6Address             string `json:"inet6_address,omitempty"`
 	AutoRoute                bool   `json:"auto_route,omitempty"`
 	StrictRoute              bool   `json:"strict_route,omitempty"`
 	Stack                    string `json:"stack,omitempty"`
@@ -103,6 +125,7 @@ type InboundConfig struct {
 	DomainStrategy           string `json:"domain_strategy,omitempty"`
 	EndpointIndependentNat   bool   `json:"endpoint_independent_nat,omitempty"`
 	UDPTimeout               int    `json:"udp_timeout,omitempty"`
+	SniffTimeout             string `json:"sniff_timeout,omitempty"`
 }
 
 // PlatformConfig 平台特定配置
@@ -151,6 +174,7 @@ type ECHConfig struct {
 	Enabled     bool     `json:"enabled,omitempty"`
 	PEMKey      string   `json:"pem_key,omitempty"`
 	Configs     []string `json:"configs,omitempty"`
+	Config      []string `json:"config,omitempty"` // 出站使用的 PEM 格式 ECH 配置
 	DynamicPath string   `json:"dynamic_path,omitempty"`
 }
 
@@ -170,18 +194,19 @@ type RealityConfig struct {
 // TransportConfig 传输层配置
 type TransportConfig struct {
 	Type        string            `json:"type,omitempty"`
+	Host        interface{}       `json:"host,omitempty"` // http 为域名列表，httpupgrade 为单个域名
 	Path        string            `json:"path,omitempty"`
 	ServiceName string            `json:"service_name,omitempty"`
 	MaxIdleTime string            `json:"max_idle_time,omitempty"`
 	PingTimeout string            `json:"ping_timeout,omitempty"`
 	Headers     map[string]string `json:"headers,omitempty"`
-	EarlyData   *EarlyDataConfig  `json:"early_data,omitempty"`
+	*EarlyDataConfig
 }
 
-// EarlyDataConfig 早期数据配置
+// EarlyDataConfig ws 早期数据配置
 type EarlyDataConfig struct {
-	Header_name string `json:"header_name,omitempty"`
-	Bytes       int    `json:"bytes,omitempty"`
+	HeaderName   string `json:"early_data_header_name,omitempty"`
+	MaxEarlyData int    `json:"max_early_data,omitempty"`
 }
 
 // SelectorConfig 选择器配置
@@ -217,11 +242,32 @@ type OutboundConfig struct {
 	DomainStrategy string           `json:"domain_strategy,omitempty"`
 	FallbackDelay  string           `json:"fallback_delay,omitempty"`
 	Detour         string           `json:"detour,omitempty"`
+	TCPFastOpen    bool             `json:"tcp_fast_open,omitempty"`
+	UDPOverTCP     bool             `json:"udp_over_tcp,omitempty"`
 	Outbounds      []string         `json:"outbounds,omitempty"`
 	Default        string           `json:"default,omitempty"`
 	URL            string           `json:"url,omitempty"`
 	Interval       string           `json:"interval,omitempty"`
 	Tolerance      int              `json:"tolerance,omitempty"`
+
+	// Shadowsocks
+	Plugin     string `json:"plugin,omitempty"`
+	PluginOpts string `json:"plugin_opts,omitempty"`
+
+	// Hysteria2
+	UpMbps   int                  `json:"up_mbps,omitempty"`
+	DownMbps int                  `json:"down_mbps,omitempty"`
+	Obfs     *Hysteria2ObfsConfig `json:"obfs,omitempty"`
+
+	// TUIC
+	CongestionControl string `json:"congestion_control,omitempty"`
+	UDPRelayMode      string `json:"udp_relay_mode,omitempty"`
+}
+
+// Hysteria2ObfsConfig Hysteria2 混淆配置
+type Hysteria2ObfsConfig struct {
+	Type     string `json:"type"`
+	Password string `json:"password"`
 }
 
 // MultiplexConfig 多路复用配置
@@ -231,6 +277,7 @@ type MultiplexConfig struct {
 	MaxConnections int    `json:"max_connections,omitempty"`
 	MinStreams     int    `json:"min_streams,omitempty"`
 	MaxStreams     int    `json:"max_streams,omitempty"`
+	Padding        bool   `json:"padding,omitempty"`
 }
 
 // FilterRule 过滤规则
@@ -250,16 +297,21 @@ type RouteConfig struct {
 
 // RouteRule 路由规则
 type RouteRule struct {
-	Type        string      `json:"type,omitempty"`
-	Mode        string      `json:"mode,omitempty"`
-	Domain      []string    `json:"domain,omitempty"`
-	IPIsPrivate bool        `json:"ip_is_private,omitempty"`
-	Protocol    []string    `json:"protocol,omitempty"`
-	Port        int         `json:"port,omitempty"`
-	RuleSet     []string    `json:"rule_set,omitempty"`
-	Outbound    string      `json:"outbound"`
-	Rules       []RouteRule `json:"rules,omitempty"`
-	ClashMode   string      `json:"clash_mode,omitempty"`
+	Type          string      `json:"type,omitempty"`
+	Mode          string      `json:"mode,omitempty"`
+	Domain        []string    `json:"domain,omitempty"`
+	DomainSuffix  []string    `json:"domain_suffix,omitempty"`
+	DomainKeyword []string    `json:"domain_keyword,omitempty"`
+	IPCIDR        []string    `json:"ip_cidr,omitempty"`
+	IPIsPrivate   bool        `json:"ip_is_private,omitempty"`
+	Inbound       []string    `json:"inbound,omitempty"`
+	Protocol      []string    `json:"protocol,omitempty"`
+	Port          int         `json:"port,omitempty"`
+	RuleSet       []string    `json:"rule_set,omitempty"`
+	Invert        bool        `json:"invert,omitempty"`
+	Outbound      string      `json:"outbound"`
+	Rules         []RouteRule `json:"rules,omitempty"`
+	ClashMode     string      `json:"clash_mode,omitempty"`
 }
 
 // RuleSetConfig 规则集配置
@@ -285,6 +337,7 @@ type SingBoxConfig struct {
 // SingBoxGenerator sing-box 配置生成器
 type SingBoxGenerator struct {
 	storage storage.Storage
+	logger  logrus.FieldLogger
 }
 
 const (
@@ -295,6 +348,7 @@ const (
 func NewSingBoxGenerator(storage storage.Storage) *SingBoxGenerator {
 	return &SingBoxGenerator{
 		storage: storage,
+		logger:  logrus.StandardLogger(),
 	}
 }
 
@@ -324,78 +378,102 @@ func (g *SingBoxGenerator) GenerateConfig() ([]byte, error) {
 	// 获取入站模式
 	inboundMode := settings.GetInboundMode()
 
+	// 补全入站设置
+	if err := settings.ValidateInbound(); err != nil {
+		return nil, fmt.Errorf("validate inbound settings: %w", err)
+	}
+	if err := settings.ValidateURLTest(); err != nil {
+		return nil, fmt.Errorf("validate url test settings: %w", err)
+	}
+
+	// 获取 DNS 设置
+	dnsSettings, err := g.storage.GetDNSSettings()
+	if err != nil {
+		return nil, fmt.Errorf("get dns settings: %w", err)
+	}
+
+	// 入站和 DNS 使用相同的域名解析策略
+	domainStrategy := resolveDomainStrategy(settings.EnableIPv6, dnsSettings.Strategy)
+	dnsSettings.Strategy = domainStrategy
+
+	// 启用 IPv6 时为 TUN 分配 IPv6 地址
+	var tunInet6Address string
+	if settings.EnableIPv6 {
+		tunInet6Address = settings.TunInet6Address
+	}
+
 	// 生成入站配置
 	var inbounds []InboundConfig
 	switch inboundMode {
 	case "tun":
 		// TUN 模式配置
 		inbounds = append(inbounds, InboundConfig{
-			Type:                     "tun",
-			Tag:                      "tun-in",
-			InterfaceName:            tunInterface,
-			Inet4Address:             "172.19.0.1/30",
-			AutoRoute:                true,
-			StrictRoute:              true,
-			Stack:                    "system",
-			Sniff:                    true,
-			SniffOverrideDestination: true,
-			DomainStrategy:           "ipv4_only",
+			Type:          "tun",
+			Tag:           "tun-in",
+			InterfaceName: tunInterface,
+			Inet4Address:  settings.TunInet4Address,
+			Inet6Address:  tunInet6Address,
+			AutoRoute:     true,
+			StrictRoute:   true,
+			Stack:         settings.TunStack,
 		})
 		// 添加 mixed 入站
 		inbounds = append(inbounds, InboundConfig{
-			Type:                     "mixed",
-			Tag:                      "mixed-in",
-			Listen:                   "::",
-			Listen_Port:              7890,
-			Sniff:                    true,
-			SniffOverrideDestination: true,
-			DomainStrategy:           "ipv4_only",
+			Type:        "mixed",
+			Tag:         "mixed-in",
+			Listen:      settings.ListenAddress,
+			Listen_Port: settings.ProxyPort,
 		})
 	case "redirect":
 		// Redirect TCP + TProxy UDP 模式配置
 		inbounds = append(inbounds,
 			InboundConfig{
-				Type:                     "redirect",
-				Tag:                      "redirect-in",
-				Listen:                   "::",
-				Listen_Port:              7892,
-				Sniff:                    true,
-				SniffOverrideDestination: true,
-				DomainStrategy:           "ipv4_only",
+				Type:        "redirect",
+				Tag:         "redirect-in",
+				Listen:      settings.ListenAddress,
+				Listen_Port: settings.RedirectPort,
 			},
 			InboundConfig{
-				Type:                     "tproxy",
-				Tag:                      "tproxy-in",
-				Listen:                   "::",
-				Listen_Port:              7893,
-				Sniff:                    true,
-				SniffOverrideDestination: true,
-				DomainStrategy:           "ipv4_only",
+				Type:        "tproxy",
+				Tag:         "tproxy-in",
+				Listen:      settings.ListenAddress,
+				Listen_Port: settings.TProxyPort,
 			},
 		)
 	default:
 		// 默认使用 TUN 模式
 		inbounds = append(inbounds, InboundConfig{
-			Type:                     "tun",
-			Tag:                      "tun-in",
-			InterfaceName:            tunInterface,
-			Inet4Address:             "172.19.0.1/30",
-			AutoRoute:                true,
-			StrictRoute:              true,
-			Stack:                    "system",
-			Sniff:                    true,
-			SniffOverrideDestination: true,
-			DomainStrategy:           "ipv4_only",
+			Type:          "tun",
+			Tag:           "tun-in",
+			InterfaceName: tunInterface,
+			Inet4Address:  settings.TunInet4Address,
+			Inet6Address:  tunInet6Address,
+			AutoRoute:     true,
+			StrictRoute:   true,
+			Stack:         settings.TunStack,
 		})
 		// 添加 mixed 入站
 		inbounds = append(inbounds, InboundConfig{
-			Type:                     "mixed",
-			Tag:                      "mixed-in",
-			Listen:                   "::",
-			Listen_Port:              7890,
-			Sniff:                    true,
-			SniffOverrideDestination: true,
-			DomainStrategy:           "ipv4_only",
+			Type:        "mixed",
+			Tag:         "mixed-in",
+			Listen:      settings.ListenAddress,
+			Listen_Port: settings.ProxyPort,
+		})
+	}
+
+	// 应用各入站的嗅探与解析选项
+	inboundOptions := settings.GetInboundOptions()
+	for i := range inbounds {
+		applyInboundOptions(&inbounds[i], inboundOptions, domainStrategy)
+	}
+
+	// 局域网 DNS 入站
+	if settings.DNSInboundEnabled() {
+		inbounds = append(inbounds, InboundConfig{
+			Type:        "direct",
+			Tag:         "dns-in",
+			Listen:      settings.ListenAddress,
+			Listen_Port: settings.DNSPort,
 		})
 	}
 
@@ -410,13 +488,143 @@ func (g *SingBoxGenerator) GenerateConfig() ([]byte, error) {
 	// 根据面板类型生成路径
 	dashboardPath := fmt.Sprintf("bin/web/%s", dashboard.Type)
 
-	// 获取 DNS 设置
-	dnsSettings, err := g.storage.GetDNSSettings()
+	// 生成出站配置
+	outbounds, aliases, err := g.generateOutbounds(settings)
 	if err != nil {
-		return nil, fmt.Errorf("get dns settings: %w", err)
+		return nil, err
 	}
 
-	// 生成出站配置
+	// 生成规则集配置
+	var ruleSetConfigs []RuleSetConfig
+	var rules []RouteRule
+
+	// 从数据库获取所有规则集
+	dbRuleSets, err := g.storage.GetRuleSets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rule sets: %v", err)
+	}
+
+	// 添加所有规则集配置
+	ruleSetMap := make(map[string]bool)
+
+	// 首先添加数据库中的规则集
+	for _, ruleSet := range dbRuleSets {
+		if !ruleSet.Enabled {
+			continue
+		}
+		// 广告规则集由广告拦截开关控制
+		if ruleSet.ID == ruleset.AdBlockRuleSetTag {
+			continue
+		}
+		if !ruleSetMap[ruleSet.ID] {
+			ruleSetMap[ruleSet.ID] = true
+			ruleSetConfigs = append(ruleSetConfigs, RuleSetConfig{
+				Tag:    ruleSet.ID,
+				Type:   "local",
+				Format: "binary",
+				Path:   fmt.Sprintf("./configs/sing-box/rules/%s.srs", ruleSet.ID),
+			})
+			// 添加规则，出站不存在时只保留规则集
+			outbound, ok := resolveOutboundTag(ruleSet.Outbound, outboundTagSet(outbounds), aliases)
+			if !ok {
+				g.logger.Warnf("Skip rule of rule set %s: unknown outbound %s", ruleSet.Name, ruleSet.Outbound)
+				continue
+			}
+			rules = append(rules, RouteRule{
+				RuleSet:  []string{ruleSet.ID},
+				Outbound: outbound,
+			})
+		}
+	}
+
+	// 用户自定义规则优先于规则集规则
+	userRules, err := g.generateUserRules(outbounds, aliases)
+	if err != nil {
+		return nil, err
+	}
+	// 广告拦截规则位于用户规则之后、其他规则集规则之前
+	if dnsSettings.EnableAdBlock {
+		ruleSetConfigs = append(ruleSetConfigs, RuleSetConfig{
+			Tag:    ruleset.AdBlockRuleSetTag,
+			Type:   "local",
+			Format: "binary",
+			Path:   fmt.Sprintf("./configs/sing-box/rules/%s.srs", ruleset.AdBlockRuleSetTag),
+		})
+		rules = append([]RouteRule{buildAdBlockRouteRule(dnsSettings.AdBlockAllowlist)}, rules...)
+	}
+	rules = append(userRules, rules...)
+
+	// 添加基本路由规则
+	dnsRule := RouteRule{
+		Type:     "logical",
+		Mode:     "or",
+		Outbound: "dns-out",
+		Rules: []RouteRule{
+			{
+				Port:     53,
+				Outbound: "",
+			},
+			{
+				Protocol: []string{"dns"},
+				Outbound: "",
+			},
+		},
+	}
+	if settings.DNSInboundEnabled() {
+		dnsRule.Rules = append(dnsRule.Rules, RouteRule{
+			Inbound:  []string{"dns-in"},
+			Outbound: "",
+		})
+	}
+	rules = append([]RouteRule{dnsRule}, rules...)
+
+	// 生成 DNS 配置
+	dnsConfig, err := g.generateDNSConfig(dnsSettings, outbounds, aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	// 生成完整配置
+	config := &SingBoxConfig{
+		Log: &LogConfig{
+			Level:     "info",
+			Timestamp: true,
+			Output:    "",
+			Disabled:  false,
+		},
+		DNS:       dnsConfig,
+		Inbounds:  inbounds,
+		Outbounds: outbounds,
+		Route: &RouteConfig{
+			AutoDetectInterface: true,
+			Final:               "节点选择",
+			Rules:               rules,
+			RuleSet:             ruleSetConfigs,
+			OverrideAndroidVPN:  true,
+		},
+		Experimental: &ExperimentalConfig{
+			ClashAPI: &ClashAPIConfig{
+				ExternalController: "0.0.0.0:9090",
+				ExternalUI:         dashboardPath,
+				Secret:             "",
+				DefaultMode:        "rule",
+			},
+			CacheFile: &CacheFileConfig{
+				// FakeIP 模式下持久化映射，避免重启后地址失效
+				Enabled:     dnsSettings.EnableFakeIP,
+				Path:        "cache.db",
+				StoreFakeIP: dnsSettings.EnableFakeIP,
+			},
+		},
+	}
+
+	// 序列化配置（使用格式化输出）
+	return json.MarshalIndent(config, "", "  ")
+}
+
+// generateOutbounds 生成节点、节点组和策略组出站，返回出站列表以及
+// 节点组、策略组和节点的 ID 或名称到出站标签的映射
+func (g *SingBoxGenerator) generateOutbounds(settings *models.Settings) ([]OutboundConfig, map[string]string, error) {
 	var outbounds []OutboundConfig
 
 	// 添加直连出站
@@ -440,27 +648,21 @@ func (g *SingBoxGenerator) GenerateConfig() ([]byte, error) {
 	// 获取节点组
 	nodeGroups, err := g.storage.GetNodeGroups()
 	if err != nil {
-		return nil, fmt.Errorf("get node groups: %w", err)
+		return nil, nil, fmt.Errorf("get node groups: %w", err)
 	}
 
 	// 获取所有节点
 	nodes, err := g.storage.GetNodes()
 	if err != nil {
-		return nil, fmt.Errorf("get nodes: %w", err)
+		return nil, nil, fmt.Errorf("get nodes: %w", err)
 	}
 
 	// 为每个节点组匹配节点
 	for i := range nodeGroups {
 		nodeGroups[i].Nodes = nil // 清空现有节点
-		if nodeGroups[i].Name == "全部" {
-			// "全部"分组包含所有节点
-			nodeGroups[i].Nodes = append(nodeGroups[i].Nodes, nodes...)
-		} else {
-			// 其他分组按照匹配规则添加节点
-			for _, node := range nodes {
-				if nodeGroups[i].MatchNode(&node) {
-					nodeGroups[i].Nodes = append(nodeGroups[i].Nodes, node)
-				}
+		for _, node := range nodes {
+			if nodeGroups[i].Contains(&node) {
+				nodeGroups[i].Nodes = append(nodeGroups[i].Nodes, node)
 			}
 		}
 	}
@@ -468,7 +670,10 @@ func (g *SingBoxGenerator) GenerateConfig() ([]byte, error) {
 	// 创建节点组出站
 	var nodeOutboundMap = make(map[string][]string) // 存储每个组的节点出站
 
-	// 1. 首先收集所有节点出站
+	// 1. 首先收集所有节点出站，每个节点只生成一次，节点组引用相同的标签
+	nodeTags := make(map[string]string) // 节点 ID -> 出站标签
+	usedTags := make(map[string]bool)
+	skippedNodes := make(map[string]bool) // 无法生成出站的节点 ID
 	for _, group := range nodeGroups {
 		if !group.Active {
 			continue
@@ -477,15 +682,24 @@ func (g *SingBoxGenerator) GenerateConfig() ([]byte, error) {
 		// 收集组内节点
 		var nodeOutbounds []string
 		for _, node := range group.Nodes {
-			// 生成唯一的出站标签
-			nodeTag := fmt.Sprintf("%s-%s", group.ID, node.Name)
-			outbound, err := g.generateOutbound(&node)
+			if nodeTag, ok := nodeTags[node.ID]; ok {
+				nodeOutbounds = append(nodeOutbounds, nodeTag)
+				continue
+			}
+			if skippedNodes[node.ID] {
+				continue
+			}
+
+			// 订阅中的节点可能使用不支持的传输方式或插件，跳过该节点而不影响整个配置
+			outbound, err := g.generateNodeOutbound(&node, nodeGroups, usedTags)
 			if err != nil {
-				return nil, fmt.Errorf("generate outbound for node %s: %w", node.Name, err)
+				g.logger.WithError(err).Warnf("Skip node %s", node.Name)
+				skippedNodes[node.ID] = true
+				continue
 			}
-			outbound.Tag = nodeTag // 使用唯一的标签
 			outbounds = append(outbounds, outbound)
-			nodeOutbounds = append(nodeOutbounds, nodeTag)
+			nodeTags[node.ID] = outbound.Tag
+			nodeOutbounds = append(nodeOutbounds, outbound.Tag)
 		}
 
 		if len(nodeOutbounds) > 0 {
@@ -493,7 +707,7 @@ func (g *SingBoxGenerator) GenerateConfig() ([]byte, error) {
 		}
 	}
 
-	// 2. 创建所有手动节点组
+	// 2. 按节点组模式创建节点组出站
 	for _, group := range nodeGroups {
 		if !group.Active {
 			continue
@@ -501,38 +715,21 @@ func (g *SingBoxGenerator) GenerateConfig() ([]byte, error) {
 
 		nodeOutbounds := nodeOutboundMap[group.Name]
 		if len(nodeOutbounds) > 0 {
-			// 创建普通选择器
-			groupOutbound := OutboundConfig{
-				Type:      "selector",
-				Tag:       group.Name,
-				Outbounds: nodeOutbounds,
-				Default:   nodeOutbounds[0],
+			// 备份中可能包含不支持的模式，按手动选择生成
+			if !models.IsSupportedNodeGroupMode(group.Mode) {
+				g.logger.Warnf("Node group %s: mode %s is not supported by sing-box, use %s", group.Name, group.Mode, models.NodeGroupModeSelect)
 			}
-			outbounds = append(outbounds, groupOutbound)
+			outbounds = append(outbounds, buildNodeGroupOutbound(&group, nodeOutbounds, settings))
 		}
 	}
 
-	// 3. 创建所有自动节点组
-	for _, group := range nodeGroups {
-		if !group.Active {
-			continue
-		}
-
-		nodeOutbounds := nodeOutboundMap[group.Name]
-		if len(nodeOutbounds) > 0 {
-			// 创建自动测试版本
-			autoGroupName := fmt.Sprintf("%s自动", group.Name)
-			autoGroupOutbound := OutboundConfig{
-				Type:      "urltest",
-				Tag:       autoGroupName,
-				Outbounds: nodeOutbounds,
-				URL:       "http://www.gstatic.com/generate_204",
-				Interval:  "300s",
-				Tolerance: 50,
-			}
-			outbounds = append(outbounds, autoGroupOutbound)
-		}
+	// 将节点的代理链目标转换为出站标签
+	// 订阅更新后节点可能发生变化，无效的代理链只记录警告并忽略
+	outbounds, err = g.resolveDetours(outbounds, nodes, nodeGroups, nodeTags, usedTags)
+	if err != nil {
+		return nil, nil, err
 	}
+	g.dropDetourCycles(outbounds)
 
 	// 添加节点选择器
 	var selectorOutbounds []string
@@ -543,7 +740,6 @@ func (g *SingBoxGenerator) GenerateConfig() ([]byte, error) {
 		nodeOutbounds := nodeOutboundMap[group.Name]
 		if len(nodeOutbounds) > 0 {
 			selectorOutbounds = append(selectorOutbounds, group.Name)
-			selectorOutbounds = append(selectorOutbounds, fmt.Sprintf("%s自动", group.Name))
 		}
 	}
 	// 添加直连和拦截选项
@@ -556,182 +752,554 @@ func (g *SingBoxGenerator) GenerateConfig() ([]byte, error) {
 		Default:   selectorOutbounds[0], // 默认使用第一个可用的节点组
 	})
 
-	// 添加规则组选择器
-	ruleGroupOutbounds := append([]string{"节点选择"}, selectorOutbounds...)
-	outbounds = append(outbounds, OutboundConfig{
-		Type:      "selector",
-		Tag:       "🎬 流媒体",
-		Outbounds: ruleGroupOutbounds,
-		Default:   "节点选择",
-	})
+	// 规则可以使用节点组和节点的 ID 作为出站
+	aliases := buildOutboundAliases(nodeGroups, nodeTags)
 
-	outbounds = append(outbounds, OutboundConfig{
-		Type:      "selector",
-		Tag:       "💬 社交媒体",
-		Outbounds: ruleGroupOutbounds,
-		Default:   "节点选择",
-	})
+	// 添加策略组
+	policyGroups, err := g.storage.GetPolicyGroups()
+	if err != nil {
+		return nil, nil, fmt.Errorf("get policy groups: %w", err)
+	}
+	outbounds = appendPolicyGroupOutbounds(outbounds, policyGroups, selectorOutbounds, aliases)
 
-	outbounds = append(outbounds, OutboundConfig{
-		Type:      "selector",
-		Tag:       "🔍 谷歌服务",
-		Outbounds: ruleGroupOutbounds,
-		Default:   "节点选择",
-	})
+	return outbounds, aliases, nil
+}
 
-	outbounds = append(outbounds, OutboundConfig{
-		Type:      "selector",
-		Tag:       "💻 开发服务",
-		Outbounds: ruleGroupOutbounds,
-		Default:   "节点选择",
+// ResolveOutbound 按生成配置时的规则将规则或 DNS 服务器引用的出站解析为出站标签
+func (g *SingBoxGenerator) ResolveOutbound(outbound string) (string, bool, error) {
+	settings, err := g.storage.GetSettings()
+	if err != nil {
+		return "", false, fmt.Errorf("get settings: %w", err)
+	}
+	if err := settings.ValidateURLTest(); err != nil {
+		return "", false, fmt.Errorf("validate url test settings: %w", err)
+	}
+
+	outbounds, aliases, err := g.generateOutbounds(settings)
+	if err != nil {
+		return "", false, err
+	}
+	tag, ok := resolveOutboundTag(outbound, outboundTagSet(outbounds), aliases)
+	return tag, ok, nil
+}
+
+// fallbackTolerance 故障转移使用的延迟容差，当前节点可用时不会因为其他节点延迟更低而切换
+const fallbackTolerance = 65535
+
+// buildNodeGroupOutbound 根据节点组模式生成选择器或自动测速出站，未设置的测速选项使用全局设置
+// sing-box 没有故障转移出站，故障转移使用容差最大的自动测速出站，成员保持节点组中的顺序
+func buildNodeGroupOutbound(group *models.NodeGroup, nodeOutbounds []string, settings *models.Settings) OutboundConfig {
+	if group.Mode != models.NodeGroupModeURLTest && group.Mode != models.NodeGroupModeFallback {
+		return OutboundConfig{
+			Type:      "selector",
+			Tag:       group.Name,
+			Outbounds: nodeOutbounds,
+			Default:   nodeOutbounds[0],
+		}
+	}
+
+	outbound := OutboundConfig{
+		Type:      "urltest",
+		Tag:       group.Name,
+		Outbounds: nodeOutbounds,
+		URL:       group.TestURL,
+		Interval:  group.TestInterval,
+		Tolerance: group.Tolerance,
+	}
+	if outbound.URL == "" {
+		outbound.URL = settings.URLTestURL
+	}
+	if outbound.Interval == "" {
+		outbound.Interval = settings.URLTestInterval
+	}
+	if group.Mode == models.NodeGroupModeFallback {
+		outbound.Tolerance = fallbackTolerance
+	} else if outbound.Tolerance == 0 {
+		outbound.Tolerance = settings.URLTestTolerance
+	}
+	return outbound
+}
+
+// generateNodeOutbound 生成节点出站，节点未设置的出站选项使用所在节点组的设置
+func (g *SingBoxGenerator) generateNodeOutbound(node *models.Node, nodeGroups []models.NodeGroup, usedTags map[string]bool) (OutboundConfig, error) {
+	node.OutboundOptions = models.EffectiveOutboundOptions(node, nodeGroups)
+	outbound, err := g.generateOutbound(node)
+	if err != nil {
+		return outbound, fmt.Errorf("generate outbound for node %s: %w", node.Name, err)
+	}
+	outbound.Tag = nodeOutboundTag(node, usedTags)
+	usedTags[outbound.Tag] = true
+	return outbound, nil
+}
+
+// resolveDetours 将节点出站中的代理链目标转换为出站标签
+// 目标节点不在任何已启用的节点组中时单独生成该节点的出站，目标不存在或节点组为空时忽略代理链
+func (g *SingBoxGenerator) resolveDetours(outbounds []OutboundConfig, nodes []models.Node, nodeGroups []models.NodeGroup, nodeTags map[string]string, usedTags map[string]bool) ([]OutboundConfig, error) {
+	// 节点组的成员都被跳过时不会生成节点组出站
+	emitted := outboundTagSet(outbounds)
+	for i := 0; i < len(outbounds); i++ {
+		detour := outbounds[i].Detour
+		if detour == "" || !usedTags[outbounds[i].Tag] {
+			continue
+		}
+
+		node, group := models.ResolveDetour(detour, nodes, nodeGroups)
+		switch {
+		case node != nil:
+			tag, ok := nodeTags[node.ID]
+			if !ok {
+				outbound, err := g.generateNodeOutbound(node, nodeGroups, usedTags)
+				if err != nil {
+					g.logger.WithError(err).Warnf("Outbound %s: skip detour %s", outbounds[i].Tag, detour)
+					outbounds[i].Detour = ""
+					continue
+				}
+				outbounds = append(outbounds, outbound)
+				nodeTags[node.ID] = outbound.Tag
+				tag = outbound.Tag
+			}
+			outbounds[i].Detour = tag
+		case group != nil:
+			if !emitted[group.Name] {
+				g.logger.Warnf("Outbound %s: detour group %s has no outbound, skip detour", outbounds[i].Tag, group.Name)
+				outbounds[i].Detour = ""
+				continue
+			}
+			outbounds[i].Detour = group.Name
+		default:
+			g.logger.Warnf("Outbound %s: unknown detour %s, skip detour", outbounds[i].Tag, detour)
+			outbounds[i].Detour = ""
+		}
+	}
+	return outbounds, nil
+}
+
+// dropDetourCycles 删除形成循环的代理链
+// 保存时已检查循环，这里处理订阅更新或节点组匹配到新节点后产生的循环
+func (g *SingBoxGenerator) dropDetourCycles(outbounds []OutboundConfig) {
+	index := make(map[string]int, len(outbounds))
+	for i := range outbounds {
+		index[outbounds[i].Tag] = i
+	}
+	for {
+		cycle := findDetourCycle(outbounds, index)
+		if cycle == nil {
+			return
+		}
+		// 循环中只有代理链可以删除，节点组到节点的引用保持不变
+		for _, i := range cycle {
+			if outbounds[i].Detour != "" {
+				g.logger.Warnf("Outbound %s: detour %s forms a cycle, skip detour", outbounds[i].Tag, outbounds[i].Detour)
+				outbounds[i].Detour = ""
+				break
+			}
+		}
+	}
+}
+
+// findDetourCycle 查找出站之间经由代理链和节点组形成的环，返回环上出站的下标
+func findDetourCycle(outbounds []OutboundConfig, index map[string]int) []int {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make([]int, len(outbounds))
+	var path []int
+	var visit func(i int) []int
+	visit = func(i int) []int {
+		switch state[i] {
+		case visiting:
+			for j := len(path) - 1; j >= 0; j-- {
+				if path[j] == i {
+					return append([]int(nil), path[j:]...)
+				}
+			}
+		case visited:
+			return nil
+		}
+		state[i] = visiting
+		path = append(path, i)
+
+		var next []string
+		if outbounds[i].Detour != "" {
+			next = append(next, outbounds[i].Detour)
+		}
+		if outbounds[i].Type == "selector" || outbounds[i].Type == "urltest" {
+			next = append(next, outbounds[i].Outbounds...)
+		}
+		for _, tag := range next {
+			if j, ok := index[tag]; ok {
+				if cycle := visit(j); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[i] = visited
+		return nil
+	}
+	for i := range outbounds {
+		if cycle := visit(i); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// generateUserRules 将数据库中的用户规则转换为路由规则
+func (g *SingBoxGenerator) generateUserRules(outbounds []OutboundConfig, aliases map[string]string) ([]RouteRule, error) {
+	dbRules, err := g.storage.GetRules()
+	if err != nil {
+		return nil, fmt.Errorf("get rules: %w", err)
+	}
+
+	// 按优先级排序，数值越小越靠前
+	sort.SliceStable(dbRules, func(i, j int) bool {
+		return dbRules[i].Priority < dbRules[j].Priority
 	})
 
-	// 生成规则集配置
-	var ruleSetConfigs []RuleSetConfig
+	outboundTags := outboundTagSet(outbounds)
+
 	var rules []RouteRule
+	for _, dbRule := range dbRules {
+		if !dbRule.Enabled {
+			continue
+		}
 
-	// 从数据库获取所有规则集
-	dbRuleSets, err := g.storage.GetRuleSets()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get rule sets: %v", err)
+		outbound, ok := resolveOutboundTag(dbRule.Outbound, outboundTags, aliases)
+		if !ok {
+			// 出站不存在时跳过，避免生成 sing-box 无法加载的配置
+			g.logger.Warnf("Skip rule %s: unknown outbound %s", dbRule.Name, dbRule.Outbound)
+			continue
+		}
+
+		rule := RouteRule{Outbound: outbound}
+		switch dbRule.Type {
+		case "domain":
+			for _, value := range dbRule.Values {
+				switch kind, domain := parseDomainValue(value); kind {
+				case "domain":
+					rule.Domain = append(rule.Domain, domain)
+				case "domain_keyword":
+					rule.DomainKeyword = append(rule.DomainKeyword, domain)
+				case "domain_suffix":
+					rule.DomainSuffix = append(rule.DomainSuffix, domain)
+				}
+			}
+		case "ip":
+			for _, value := range dbRule.Values {
+				if cidr := parseIPValue(value); cidr != "" {
+					rule.IPCIDR = append(rule.IPCIDR, cidr)
+				}
+			}
+		default:
+			// 规则集同步生成的规则等其他类型不在这里处理
+			continue
+		}
+
+		if len(rule.Domain) == 0 && len(rule.DomainSuffix) == 0 && len(rule.DomainKeyword) == 0 && len(rule.IPCIDR) == 0 {
+			continue
+		}
+		rules = append(rules, rule)
 	}
 
-	// 添加所有规则集配置
-	ruleSetMap := make(map[string]bool)
+	return rules, nil
+}
 
-	// 首先添加数据库中的规则集
-	for _, ruleSet := range dbRuleSets {
-		if !ruleSet.Enabled {
+// parseDomainValue 解析域名匹配值，返回对应的 sing-box 匹配字段和域名
+// 支持 full:、keyword:、domain: 和 +. 前缀，无前缀时按域名后缀匹配
+func parseDomainValue(value string) (string, string) {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		return "", ""
+	case strings.HasPrefix(value, "full:"):
+		return "domain", strings.TrimPrefix(value, "full:")
+	case strings.HasPrefix(value, "keyword:"):
+		return "domain_keyword", strings.TrimPrefix(value, "keyword:")
+	case strings.HasPrefix(value, "domain:"):
+		return "domain_suffix", strings.TrimPrefix(value, "domain:")
+	case strings.HasPrefix(value, "+."):
+		return "domain_suffix", strings.TrimPrefix(value, "+.")
+	default:
+		return "domain_suffix", value
+	}
+}
+
+// parseIPValue 将 IP 或 CIDR 统一转换为 CIDR 格式
+func parseIPValue(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.Contains(value, "/") {
+		return value
+	}
+	if strings.Contains(value, ":") {
+		return value + "/128"
+	}
+	return value + "/32"
+}
+
+// applyInboundOptions 根据入站类型应用嗅探、解析策略和 UDP 超时选项
+func applyInboundOptions(inbound *InboundConfig, options *models.InboundOptionsSettings, domainStrategy string) {
+	var opts models.InboundOptions
+	switch inbound.Type {
+	case "tun":
+		opts = options.Tun
+	case "mixed":
+		opts = options.Mixed
+	case "redirect":
+		opts = options.Redirect
+	case "tproxy":
+		opts = options.TProxy
+	default:
+		return
+	}
+
+	inbound.Sniff = opts.SniffEnabled()
+	if inbound.Sniff {
+		inbound.SniffOverrideDestination = opts.OverrideDestination()
+		inbound.SniffTimeout = opts.SniffTimeout
+	}
+	inbound.DomainStrategy = domainStrategy
+	if opts.DomainStrategy != "" {
+		inbound.DomainStrategy = opts.DomainStrategy
+	}
+	inbound.UDPTimeout = opts.UDPTimeout
+}
+
+// resolveDomainStrategy 根据 IPv6 开关确定域名解析策略
+// 未启用 IPv6 时强制仅使用 IPv4，启用后 ipv4_only 视为优先 IPv4 的双栈
+func resolveDomainStrategy(enableIPv6 bool, strategy string) string {
+	if !enableIPv6 {
+		return "ipv4_only"
+	}
+	switch strategy {
+	case "", "ipv4_only":
+		return "prefer_ipv4"
+	default:
+		return strategy
+	}
+}
+
+// resolveOutboundTag 将规则中的出站名称解析为配置中实际存在的出站标签
+func resolveOutboundTag(outbound string, outboundTags map[string]bool, aliases map[string]string) (string, bool) {
+	switch outbound {
+	case "direct":
+		outbound = "direct-out"
+	case "reject":
+		outbound = "block"
+	}
+	if outboundTags[outbound] {
+		return outbound, true
+	}
+
+	// 前端可能使用节点组、策略组或节点的 ID 作为出站
+	if tag, ok := aliases[outbound]; ok && outboundTags[tag] {
+		return tag, true
+	}
+
+	return "", false
+}
+
+// outboundTagSet 收集已生成的出站标签
+func outboundTagSet(outbounds []OutboundConfig) map[string]bool {
+	tags := make(map[string]bool, len(outbounds))
+	for _, outbound := range outbounds {
+		tags[outbound.Tag] = true
+	}
+	return tags
+}
+
+// buildOutboundAliases 生成节点组 ID、节点组 Tag 和节点 ID 到出站标签的映射
+func buildOutboundAliases(nodeGroups []models.NodeGroup, nodeTags map[string]string) map[string]string {
+	aliases := make(map[string]string, len(nodeGroups)*2+len(nodeTags))
+	for id, tag := range nodeTags {
+		aliases[id] = tag
+	}
+	for _, group := range nodeGroups {
+		aliases[group.ID] = group.Name
+		if group.Tag != "" {
+			aliases[group.Tag] = group.Name
+		}
+	}
+	return aliases
+}
+
+// appendPolicyGroupOutbounds 按用户定义生成策略组选择器，并将策略组 ID 和名称加入出站映射
+// 策略组未指定候选出站时使用节点选择、所有节点组、直连和拦截
+func appendPolicyGroupOutbounds(outbounds []OutboundConfig, policyGroups []models.PolicyGroup, selectorOutbounds []string, aliases map[string]string) []OutboundConfig {
+	outboundTags := outboundTagSet(outbounds)
+	for _, group := range policyGroups {
+		var candidates []string
+		if len(group.Outbounds) == 0 {
+			candidates = append([]string{models.PolicyOutboundProxy}, selectorOutbounds...)
+		} else {
+			for _, outbound := range group.Outbounds {
+				// 跳过已删除的节点组或节点
+				if tag, ok := resolveOutboundTag(outbound, outboundTags, aliases); ok {
+					candidates = append(candidates, tag)
+				}
+			}
+		}
+		if len(candidates) == 0 {
 			continue
 		}
-		if !ruleSetMap[ruleSet.ID] {
-			ruleSetMap[ruleSet.ID] = true
-			ruleSetConfigs = append(ruleSetConfigs, RuleSetConfig{
-				Tag:    ruleSet.ID,
-				Type:   "local",
-				Format: "binary",
-				Path:   fmt.Sprintf("./configs/sing-box/rules/%s.srs", ruleSet.ID),
-			})
-			// 添加规则
-			outbound := ruleSet.Outbound
-			if outbound == "direct" {
-				outbound = "direct-out"
+
+		defaultOutbound := candidates[0]
+		if group.Default != "" {
+			if tag, ok := resolveOutboundTag(group.Default, outboundTags, aliases); ok && slices.Contains(candidates, tag) {
+				defaultOutbound = tag
 			}
-			rules = append(rules, RouteRule{
-				RuleSet:  []string{ruleSet.ID},
-				Outbound: outbound,
-			})
 		}
+
+		tag := group.Tag()
+		outbounds = append(outbounds, OutboundConfig{
+			Type:      "selector",
+			Tag:       tag,
+			Outbounds: candidates,
+			Default:   defaultOutbound,
+		})
+		aliases[group.ID] = tag
+		aliases[group.Name] = tag
 	}
+	return outbounds
+}
 
-	// 添加基本路由规则
-	rules = append([]RouteRule{{
-		Type:     "logical",
-		Mode:     "or",
-		Outbound: "dns-out",
-		Rules: []RouteRule{
-			{
-				Port:     53,
-				Outbound: "",
-			},
-			{
-				Protocol: []string{"dns"},
-				Outbound: "",
-			},
-		},
-	}}, rules...)
+// nodeOutboundTag 根据节点名称和 ID 生成稳定的出站标签
+// 名称相同的节点通过 ID 前缀区分，前缀冲突时使用完整 ID
+func nodeOutboundTag(node *models.Node, usedTags map[string]bool) string {
+	shortID := node.ID
+	if len(shortID) > 8 {
+		shortID = shortID[:8]
+	}
+	tag := fmt.Sprintf("%s [%s]", node.Name, shortID)
+	if usedTags[tag] {
+		tag = fmt.Sprintf("%s [%s]", node.Name, node.ID)
+	}
+	return tag
+}
 
-	// 生成 DNS 配置
-	dnsConfig := &DNSConfig{
-		Servers: []DNSServerConfig{
-			{
-				Tag:             "alidns",
-				Address:         dnsSettings.Domestic,
-				AddressStrategy: "prefer_ipv4",
-				Strategy:        "ipv4_only",
-				Detour:          "direct-out",
-			},
-			{
-				Tag:             "google",
-				Address:         dnsSettings.SingboxDNS,
-				AddressResolver: "alidns",
-				Strategy:        "ipv4_only",
-			},
-		},
-		Rules: []DNSRule{
-			{
-				Outbound: "any",
-				Server:   "alidns",
-			},
-			{
-				ClashMode: "direct",
-				Server:    "alidns",
-			},
-			{
-				ClashMode: "global",
-				Server:    "google",
-			},
-			{
-				RuleSet: "geosite-cn",
-				Server:  "alidns",
-			},
-			{
-				Type: "logical",
-				Mode: "and",
-				Rules: []DNSRule{
-					{
-						RuleSet: "geosite-geolocation-!cn",
-						Invert:  true,
-					},
-					{
-						RuleSet: "geoip-cn",
-					},
-				},
-				Server:       "google",
-				ClientSubnet: dnsSettings.EDNSClientSubnet,
-			},
-		},
-		Strategy:         "ipv4_only",
-		DisableCache:     false,
-		DisableExpire:    false,
-		IndependentCache: false,
-		Final:            "google",
+// nodeServerName 获取节点的 TLS 服务器名称，订阅解析器可能将 SNI 存放在 Host 中
+func nodeServerName(node *models.Node) string {
+	if node.SNI != "" {
+		return node.SNI
 	}
+	if node.Host != "" {
+		return node.Host
+	}
+	return node.Address
+}
 
-	// 生成完整配置
-	config := &SingBoxConfig{
-		Log: &LogConfig{
-			Level:     "info",
-			Timestamp: true,
-			Output:    "",
-			Disabled:  false,
-		},
-		DNS:       dnsConfig,
-		Inbounds:  inbounds,
-		Outbounds: outbounds,
-		Route: &RouteConfig{
-			AutoDetectInterface: true,
-			Final:               "节点选择",
-			Rules:               rules,
-			RuleSet:             ruleSetConfigs,
-			OverrideAndroidVPN:  true,
-		},
-		Experimental: &ExperimentalConfig{
-			ClashAPI: &ClashAPIConfig{
-				ExternalController: "0.0.0.0:9090",
-				ExternalUI:         dashboardPath,
-				Secret:             "",
-				DefaultMode:        "rule",
-			},
-			CacheFile: &CacheFileConfig{
-				Enabled: false,
-				Path:    "cache.db",
-			},
-		},
+// nodeTLSConfig 根据节点设置生成出站 TLS 配置
+// 基于 QUIC 的协议不支持 uTLS 和 REALITY
+func nodeTLSConfig(node *models.Node, alpn []string, quic bool) *TLSConfig {
+	tls := &TLSConfig{
+		Enabled:    true,
+		ServerName: nodeServerName(node),
+		ALPN:       alpn,
+		Insecure:   node.SkipCertVerify,
+		MinVersion: node.TLSMinVersion,
+		MaxVersion: node.TLSMaxVersion,
 	}
 
-	// 序列化配置（使用格式化输出）
-	return json.MarshalIndent(config, "", "  ")
+	if node.ECH {
+		tls.ECH = &ECHConfig{Enabled: true}
+		if config := strings.TrimSpace(node.ECHConfig); config != "" {
+			tls.ECH.Config = strings.Split(config, "\n")
+		}
+	}
+
+	if quic {
+		return tls
+	}
+
+	fingerprint := node.Fingerprint
+	// sing-box 没有 spider_x 选项，SpiderX 只保存在节点中
+	if node.PublicKey != "" {
+		tls.Reality = &RealityConfig{
+			Enabled:   true,
+			PublicKey: node.PublicKey,
+			ShortID:   node.ShortID,
+		}
+		// sing-box 要求 REALITY 必须启用 uTLS
+		if fingerprint == "" {
+			fingerprint = models.DefaultUTLSFingerprint
+		}
+	}
+	if fingerprint != "" {
+		tls.UTLS = &UTLSConfig{
+			Enabled:     true,
+			Fingerprint: fingerprint,
+		}
+	}
+
+	return tls
+}
+
+// applyOutboundOptions 设置多路复用、TCP Fast Open 和 UDP over TCP
+func applyOutboundOptions(outbound *OutboundConfig, node *models.Node) {
+	switch outbound.Type {
+	case "shadowsocks", "vmess", "vless", "trojan":
+	default:
+		// 其他类型基于 QUIC 或不是代理节点
+		return
+	}
+
+	if node.TCPFastOpen != nil {
+		outbound.TCPFastOpen = *node.TCPFastOpen
+	}
+	// UDP over TCP 仅支持 Shadowsocks，且与多路复用互斥
+	if outbound.Type == "shadowsocks" && node.UDPOverTCP != nil && *node.UDPOverTCP {
+		outbound.UDPOverTCP = true
+		return
+	}
+
+	mux := node.Multiplex
+	// XTLS flow 不支持多路复用
+	if mux == nil || !mux.Enabled || outbound.Flow != "" {
+		return
+	}
+	outbound.Multiplex = &MultiplexConfig{
+		Enabled:        true,
+		Protocol:       mux.Protocol,
+		MaxConnections: mux.MaxConnections,
+		MinStreams:     mux.MinStreams,
+		MaxStreams:     mux.MaxStreams,
+		Padding:        mux.Padding,
+	}
+}
+
+// parseBandwidthMbps 解析带宽设置，支持 "100"、"100 Mbps"、"1 Gbps" 等格式
+func parseBandwidthMbps(value string) int {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return 0
+	}
+
+	multiplier := 1.0
+	for _, unit := range []struct {
+		suffix string
+		factor float64
+	}{
+		{"gbps", 1000},
+		{"mbps", 1},
+		{"kbps", 0.001},
+		{"g", 1000},
+		{"m", 1},
+		{"k", 0.001},
+	} {
+		if strings.HasSuffix(value, unit.suffix) {
+			multiplier = unit.factor
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number <= 0 {
+		return 0
+	}
+	return int(number * multiplier)
 }
 
 // ValidateConfig 验证配置
@@ -756,86 +1324,73 @@ func (g *SingBoxGenerator) generateOutbound(node *models.Node) (OutboundConfig,
 		outbound.UUID = node.UUID
 		outbound.Security = "auto"
 		if node.TLS {
-			outbound.TLS = &TLSConfig{
-				Enabled:    true,
-				ServerName: node.SNI,
-				ALPN:       node.ALPN,
-				Insecure:   node.SkipCertVerify,
-			}
+			outbound.TLS = nodeTLSConfig(node, node.ALPN, false)
 		}
-		if node.Network != "" && node.Network != "tcp" {
-			outbound.Transport = &TransportConfig{
-				Type: node.Network,
-				Path: node.Path,
-				Headers: map[string]string{
-					"Host": node.Host,
-				},
-			}
-			if node.Network == "grpc" {
-				outbound.Transport.ServiceName = node.ServiceName
-			}
+		transport, err := buildTransportConfig(node)
+		if err != nil {
+			return outbound, err
 		}
+		outbound.Transport = transport
 	case "vless":
 		outbound.UUID = node.UUID
-		if node.TLS {
-			outbound.TLS = &TLSConfig{
-				Enabled:    true,
-				ServerName: node.SNI,
-				ALPN:       node.ALPN,
-				Insecure:   node.SkipCertVerify,
-			}
+		// REALITY 节点始终启用 TLS
+		if node.TLS || node.PublicKey != "" {
+			outbound.TLS = nodeTLSConfig(node, node.ALPN, false)
 			if node.Flow != "" {
 				outbound.Flow = node.Flow
 			}
-			if node.PublicKey != "" {
-				outbound.TLS.Reality = &RealityConfig{
-					Enabled:   true,
-					PublicKey: node.PublicKey,
-					ShortID:   node.ShortID,
-				}
-			}
 		}
-		if node.Network != "" && node.Network != "tcp" {
-			outbound.Transport = &TransportConfig{
-				Type: node.Network,
-				Path: node.Path,
-				Headers: map[string]string{
-					"Host": node.Host,
-				},
-			}
-			if node.Network == "grpc" {
-				outbound.Transport.ServiceName = node.ServiceName
-			}
+		transport, err := buildTransportConfig(node)
+		if err != nil {
+			return outbound, err
 		}
+		outbound.Transport = transport
 	case "trojan":
 		outbound.Password = node.Password
 		if node.TLS {
-			outbound.TLS = &TLSConfig{
-				Enabled:    true,
-				ServerName: node.SNI,
-				ALPN:       node.ALPN,
-				Insecure:   node.SkipCertVerify,
-			}
+			outbound.TLS = nodeTLSConfig(node, node.ALPN, false)
 		}
-		if node.Network != "" && node.Network != "tcp" {
-			outbound.Transport = &TransportConfig{
-				Type: node.Network,
-				Path: node.Path,
-				Headers: map[string]string{
-					"Host": node.Host,
-				},
-			}
-			if node.Network == "grpc" {
-				outbound.Transport.ServiceName = node.ServiceName
-			}
+		transport, err := buildTransportConfig(node)
+		if err != nil {
+			return outbound, err
 		}
+		outbound.Transport = transport
 	case "shadowsocks", "ss":
 		outbound.Type = "shadowsocks"
 		outbound.Method = node.Method
 		outbound.Password = node.Password
 		if node.Plugin != "" {
-			// TODO: 添加插件支持
+			plugin, pluginOpts, err := models.NormalizeSSPluginString(node.Plugin, node.PluginOpts)
+			if err != nil {
+				return outbound, err
+			}
+			outbound.Plugin = plugin
+			outbound.PluginOpts = pluginOpts
+		}
+	case "hy2", "hysteria2":
+		outbound.Type = "hysteria2"
+		outbound.Password = node.Password
+		outbound.UpMbps = parseBandwidthMbps(node.Up)
+		outbound.DownMbps = parseBandwidthMbps(node.Down)
+		if node.Obfs != "" {
+			outbound.Obfs = &Hysteria2ObfsConfig{
+				Type:     node.Obfs,
+				Password: node.ObfsPassword,
+			}
 		}
+		// Hysteria2 必须使用 TLS
+		outbound.TLS = nodeTLSConfig(node, node.ALPN, true)
+	case "tuic":
+		outbound.UUID = node.UUID
+		outbound.Password = node.Password
+		outbound.CongestionControl = node.CC
+		outbound.UDPRelayMode = node.UDPRelayMode
+		// TUIC 必须使用 TLS，未指定 ALPN 时使用 h3
+		alpn := []string(node.ALPN)
+		if len(alpn) == 0 {
+			alpn = []string{"h3"}
+		}
+		outbound.TLS = nodeTLSConfig(node, alpn, true)
 	case "direct", "block", "dns":
 		// 无需额外配置
 	case "selector":
@@ -847,12 +1402,23 @@ func (g *SingBoxGenerator) generateOutbound(node *models.Node) (OutboundConfig,
 	case "urltest":
 		outbound.Type = "urltest"
 		outbound.Outbounds = node.GroupIDs
-		outbound.URL = "http://www.gstatic.com/generate_204"
-		outbound.Interval = "300s"
-		outbound.Tolerance = 50
+		settings, err := g.storage.GetSettings()
+		if err != nil {
+			return outbound, fmt.Errorf("get settings: %w", err)
+		}
+		if err := settings.ValidateURLTest(); err != nil {
+			return outbound, err
+		}
+		outbound.URL = settings.URLTestURL
+		outbound.Interval = settings.URLTestInterval
+		outbound.Tolerance = settings.URLTestTolerance
 	default:
 		return outbound, fmt.Errorf("unsupported node type: %s", node.Type)
 	}
 
+	applyOutboundOptions(&outbound, node)
+	// 代理链目标在生成所有出站后转换为出站标签
+	outbound.Detour = node.Detour
+
 	return outbound, nil
 }
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"singdns/api/models"
+)
+
+func TestGenerateUserRulesSkipsUnknownOutbounds(t *testing.T) {
+	g := newTestGenerator()
+	g.storage = &fakeStorage{rules: []models.Rule{
+		{Name: "proxied", Type: "domain", Values: models.StringArray{"example.com"}, Outbound: "香港", Enabled: true, Priority: 2},
+		{Name: "stale", Type: "domain", Values: models.StringArray{"example.org"}, Outbound: "deleted-group", Enabled: true},
+		{Name: "direct", Type: "ip", Values: models.StringArray{"10.0.0.0/8"}, Outbound: "direct", Enabled: true, Priority: 1},
+	}}
+	outbounds := []OutboundConfig{{Type: "selector", Tag: "香港 🇭🇰"}, {Type: "direct", Tag: "direct-out"}}
+	aliases := map[string]string{"香港": "香港 🇭🇰"}
+
+	rules, err := g.generateUserRules(outbounds, aliases)
+	if err != nil {
+		t.Fatalf("generateUserRules() error = %v", err)
+	}
+	want := []RouteRule{
+		{IPCIDR: []string{"10.0.0.0/8"}, Outbound: "direct-out"},
+		{DomainSuffix: []string{"example.com"}, Outbound: "香港 🇭🇰"},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("generateUserRules() = %+v, want %+v", rules, want)
+	}
+}
+
+func TestParseBandwidthMbps(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{value: "", want: 0},
+		{value: "100", want: 100},
+		{value: " 100 Mbps ", want: 100},
+		{value: "50m", want: 50},
+		{value: "1 Gbps", want: 1000},
+		{value: "1.5g", want: 1500},
+		{value: "2000 kbps", want: 2},
+		{value: "500k", want: 0},
+		{value: "0", want: 0},
+		{value: "-10", want: 0},
+		{value: "fast", want: 0},
+		{value: "100 MB/s", want: 0},
+	}
+	for _, tt := range tests {
+		if got := parseBandwidthMbps(tt.value); got != tt.want {
+			t.Errorf("parseBandwidthMbps(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBuildNodeGroupOutboundFallback(t *testing.T) {
+	settings := &models.Settings{URLTestURL: "https://www.gstatic.com/generate_204", URLTestInterval: "3m", URLTestTolerance: 50}
+	group := &models.NodeGroup{Name: "香港", Mode: models.NodeGroupModeFallback, Tolerance: 100}
+
+	outbound := buildNodeGroupOutbound(group, []string{"HK1", "HK2"}, settings)
+	if outbound.Type != "urltest" || outbound.Tolerance != fallbackTolerance {
+		t.Errorf("fallback outbound type = %s, tolerance = %d, want urltest, %d", outbound.Type, outbound.Tolerance, fallbackTolerance)
+	}
+	if !reflect.DeepEqual(outbound.Outbounds, []string{"HK1", "HK2"}) {
+		t.Errorf("fallback outbound members = %v, want [HK1 HK2]", outbound.Outbounds)
+	}
+}
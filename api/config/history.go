@@ -0,0 +1,112 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"singdns/api/models"
+	"singdns/api/storage"
+
+	"github.com/google/uuid"
+)
+
+// History 记录每次应用的 sing-box 配置，支持比较和回滚
+type History struct {
+	storage    storage.Storage
+	configPath string
+	binPath    string
+}
+
+// NewHistory 创建配置版本历史
+func NewHistory(storage storage.Storage, configPath, binPath string) *History {
+	return &History{
+		storage:    storage,
+		configPath: configPath,
+		binPath:    binPath,
+	}
+}
+
+// WithStorage 返回使用 store 记录版本的配置历史，用于在事务中记录版本
+func (h *History) WithStorage(store storage.Storage) *History {
+	return &History{
+		storage:    store,
+		configPath: h.configPath,
+		binPath:    h.binPath,
+	}
+}
+
+// Apply 检查并写入配置文件，成功后记录为新版本，版本的 Checked 表示是否通过了 sing-box check
+func (h *History) Apply(data []byte, trigger, user string) (*models.ConfigVersion, error) {
+	checked, err := ApplyConfig(data, h.configPath, h.binPath)
+	if err != nil {
+		return nil, err
+	}
+	return h.record(data, trigger, user, checked)
+}
+
+// Record 记录未经 sing-box check 的配置版本，内容与最新版本相同时不重复记录
+func (h *History) Record(data []byte, trigger, user string) (*models.ConfigVersion, error) {
+	return h.record(data, trigger, user, false)
+}
+
+// record 记录配置版本，内容与最新版本相同时只更新检查结果
+func (h *History) record(data []byte, trigger, user string, checked bool) (*models.ConfigVersion, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if latest, err := h.storage.GetLatestConfigVersion(); err == nil && latest.Hash == hash {
+		if checked && !latest.Checked {
+			latest.Checked = true
+			if err := h.storage.SaveConfigVersion(latest); err != nil {
+				return nil, fmt.Errorf("save config version: %w", err)
+			}
+		}
+		return latest, nil
+	}
+
+	version := &models.ConfigVersion{
+		ID:      uuid.New().String(),
+		Trigger: trigger,
+		User:    user,
+		Hash:    hash,
+		Size:    len(data),
+		Checked: checked,
+		Content: string(data),
+	}
+	if err := h.storage.SaveConfigVersion(version); err != nil {
+		return nil, fmt.Errorf("save config version: %w", err)
+	}
+
+	// 按设置清理旧版本
+	keep := models.DefaultConfigHistoryLimit
+	if settings, err := h.storage.GetSettings(); err == nil && settings.ValidateConfigHistory() == nil {
+		keep = settings.ConfigHistory
+	}
+	if err := h.storage.PruneConfigVersions(keep); err != nil {
+		return nil, fmt.Errorf("prune config versions: %w", err)
+	}
+	return version, nil
+}
+
+// Rollback 将指定版本重新应用为当前配置，并记录为新的回滚版本
+// 只改写配置文件，数据库中的节点、规则等不变，下次修改数据时会按数据库重新生成配置
+func (h *History) Rollback(id, user string) (*models.ConfigVersion, error) {
+	version, err := h.storage.GetConfigVersionByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("config version not found: %s", id)
+	}
+	return h.Apply([]byte(version.Content), models.ConfigTriggerRollback, user)
+}
+
+// Diff 比较两个配置版本
+func (h *History) Diff(fromID, toID string) ([]DiffEntry, error) {
+	from, err := h.storage.GetConfigVersionByID(fromID)
+	if err != nil {
+		return nil, fmt.Errorf("config version not found: %s", fromID)
+	}
+	to, err := h.storage.GetConfigVersionByID(toID)
+	if err != nil {
+		return nil, fmt.Errorf("config version not found: %s", toID)
+	}
+	return DiffJSON([]byte(from.Content), []byte(to.Content))
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"sync"
@@ -23,6 +24,7 @@ type Manager struct {
 	configPath string
 	settings   *models.Settings
 	storage    storage.Storage
+	history    *History
 }
 
 // NewManager 创建配置管理器
@@ -31,6 +33,7 @@ func NewManager(logger *logrus.Logger, configPath string, storage storage.Storag
 		logger:     logger,
 		configPath: configPath,
 		storage:    storage,
+		history:    NewHistory(storage, configPath, DefaultBinPath),
 	}
 }
 
@@ -55,11 +58,15 @@ func (m *Manager) UpdateSettings(settings *models.Settings) error {
 		return err
 	}
 
-	// 保存配置文件
-	if err := os.WriteFile(m.configPath, config, 0644); err != nil {
+	// 检查并保存配置文件，记录为新版本
+	version, err := m.history.Apply(config, models.ConfigTriggerSettings, "")
+	if err != nil {
 		m.logger.WithError(err).Error("Failed to write sing-box config")
 		return err
 	}
+	if !version.Checked {
+		m.logger.Warn("sing-box binary not found, config was not checked")
+	}
 
 	// 如果 sing-box 正在运行，重启服务以应用新配置
 	if m.isRunning {
@@ -76,15 +83,32 @@ func (m *Manager) UpdateSettings(settings *models.Settings) error {
 func (m *Manager) BackupConfig() (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	// TODO: Implement config backup
-	return "", nil
+
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return "", fmt.Errorf("read config file: %w", err)
+	}
+	version, err := m.history.Record(data, models.ConfigTriggerManual, "")
+	if err != nil {
+		return "", err
+	}
+	return version.ID, nil
 }
 
 // RestoreConfig restores configuration from a backup
 func (m *Manager) RestoreConfig(id string) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-	// TODO: Implement config restore
+	_, err := m.history.Rollback(id, "")
+	running := m.isRunning
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// 运行中的服务重启后使用恢复的配置
+	if running {
+		return m.RestartService()
+	}
 	return nil
 }
 
@@ -92,8 +116,7 @@ func (m *Manager) RestoreConfig(id string) error {
 func (m *Manager) DeleteBackup(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	// TODO: Implement backup deletion
-	return nil
+	return m.storage.DeleteConfigVersion(id)
 }
 
 // RestartService restarts the sing-box service
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"os"
 	"path/filepath"
 	"time"
 
@@ -18,6 +17,7 @@ type ConfigService struct {
 	configDir        string
 	dashboardManager *DashboardManager
 	storage          storage.Storage
+	history          *History
 }
 
 // NewConfigService 创建配置服务
@@ -38,7 +38,7 @@ func NewConfigService(configDir string, db storage.Storage) *ConfigService {
 			UpdateInterval:   24,
 			ProxyPort:        1080,
 			APIPort:          8080,
-			DNSPort:          53,
+			DNSPort:          models.DefaultDNSPort,
 			LogLevel:         "info",
 			EnableAutoUpdate: true,
 			UpdatedAt:        time.Now().Unix(),
@@ -60,6 +60,7 @@ func NewConfigService(configDir string, db storage.Storage) *ConfigService {
 		configDir:        configDir,
 		dashboardManager: dashboardManager,
 		storage:          db,
+		history:          NewHistory(db, filepath.Join(configDir, "config.json"), DefaultBinPath),
 	}
 
 	return service
@@ -79,14 +80,8 @@ func (s *ConfigService) GenerateConfigs() error {
 		return fmt.Errorf("format json: %w", err)
 	}
 
-	// 确保配置目录存在
-	if err := os.MkdirAll(s.configDir, 0755); err != nil {
-		return fmt.Errorf("create singbox directory: %w", err)
-	}
-
-	// 写入 SingBox 配置
-	singboxPath := filepath.Join(s.configDir, "config.json")
-	if err := os.WriteFile(singboxPath, prettyJSON.Bytes(), 0644); err != nil {
+	// 写入 SingBox 配置，通过 sing-box check 后才替换原配置并记录版本
+	if _, err := s.history.Apply(prettyJSON.Bytes(), models.ConfigTriggerManual, ""); err != nil {
 		return fmt.Errorf("write singbox config: %w", err)
 	}
 
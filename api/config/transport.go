@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"singdns/api/models"
+)
+
+// wsEarlyDataHeader Xray 风格的 ws 早期数据通过该请求头发送
+const wsEarlyDataHeader = "Sec-WebSocket-Protocol"
+
+// buildTransportConfig 根据节点的传输方式生成 V2Ray 传输层配置，tcp 或未设置时返回 nil
+func buildTransportConfig(node *models.Node) (*TransportConfig, error) {
+	switch strings.ToLower(node.Network) {
+	case "", "tcp", "raw":
+		return nil, nil
+	case "ws", "websocket":
+		path, earlyData := parseWSEarlyData(node.Path)
+		transport := &TransportConfig{
+			Type:            "ws",
+			Path:            path,
+			EarlyDataConfig: earlyData,
+		}
+		if node.Host != "" {
+			transport.Headers = map[string]string{"Host": node.Host}
+		}
+		return transport, nil
+	case "grpc", "gun":
+		// vmess 分享链接使用 path 保存 serviceName
+		serviceName := node.ServiceName
+		if serviceName == "" {
+			serviceName = node.Path
+		}
+		return &TransportConfig{
+			Type:        "grpc",
+			ServiceName: serviceName,
+		}, nil
+	case "h2", "http":
+		// HTTP 传输的 host 为域名列表
+		transport := &TransportConfig{
+			Type: "http",
+			Path: node.Path,
+		}
+		if hosts := splitTransportHosts(node.Host); len(hosts) > 0 {
+			transport.Host = hosts
+		}
+		return transport, nil
+	case "httpupgrade":
+		transport := &TransportConfig{
+			Type: "httpupgrade",
+			Path: node.Path,
+		}
+		if node.Host != "" {
+			transport.Host = node.Host
+		}
+		return transport, nil
+	case "quic":
+		return &TransportConfig{Type: "quic"}, nil
+	default:
+		return nil, fmt.Errorf("unsupported transport: %s", node.Network)
+	}
+}
+
+// parseWSEarlyData 从 ws 路径中解析 ?ed= 早期数据参数，返回去掉该参数后的路径
+func parseWSEarlyData(path string) (string, *EarlyDataConfig) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return path, nil
+	}
+	query := u.Query()
+	ed := query.Get("ed")
+	if ed == "" {
+		return path, nil
+	}
+	bytes, err := strconv.Atoi(ed)
+	if err != nil || bytes <= 0 {
+		return path, nil
+	}
+
+	query.Del("ed")
+	u.RawQuery = query.Encode()
+	return u.String(), &EarlyDataConfig{
+		HeaderName:   wsEarlyDataHeader,
+		MaxEarlyData: bytes,
+	}
+}
+
+// splitTransportHosts 拆分以逗号分隔的域名列表
+func splitTransportHosts(host string) []string {
+	var hosts []string
+	for _, h := range strings.Split(host, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
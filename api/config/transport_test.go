@@ -0,0 +1,79 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"singdns/api/models"
+)
+
+func TestBuildTransportConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		node    models.Node
+		want    *TransportConfig
+		wantErr bool
+	}{
+		{name: "empty", node: models.Node{}},
+		{name: "tcp", node: models.Node{Network: "tcp", Path: "/ignored"}},
+		{name: "raw", node: models.Node{Network: "raw"}},
+		{
+			name: "ws",
+			node: models.Node{Network: "ws", Path: "/ws", Host: "example.com"},
+			want: &TransportConfig{Type: "ws", Path: "/ws", Headers: map[string]string{"Host": "example.com"}},
+		},
+		{
+			name: "ws early data",
+			node: models.Node{Network: "WebSocket", Path: "/ws?ed=2048"},
+			want: &TransportConfig{
+				Type:            "ws",
+				Path:            "/ws",
+				EarlyDataConfig: &EarlyDataConfig{HeaderName: wsEarlyDataHeader, MaxEarlyData: 2048},
+			},
+		},
+		{
+			name: "ws invalid early data",
+			node: models.Node{Network: "ws", Path: "/ws?ed=abc"},
+			want: &TransportConfig{Type: "ws", Path: "/ws?ed=abc"},
+		},
+		{
+			name: "grpc",
+			node: models.Node{Network: "grpc", ServiceName: "svc", Path: "/ignored"},
+			want: &TransportConfig{Type: "grpc", ServiceName: "svc"},
+		},
+		{
+			name: "grpc service name from path",
+			node: models.Node{Network: "gun", Path: "svc"},
+			want: &TransportConfig{Type: "grpc", ServiceName: "svc"},
+		},
+		{
+			name: "h2",
+			node: models.Node{Network: "h2", Path: "/h2", Host: "a.example.com, b.example.com,"},
+			want: &TransportConfig{Type: "http", Path: "/h2", Host: []string{"a.example.com", "b.example.com"}},
+		},
+		{
+			name: "http without host",
+			node: models.Node{Network: "http", Path: "/"},
+			want: &TransportConfig{Type: "http", Path: "/"},
+		},
+		{
+			name: "httpupgrade",
+			node: models.Node{Network: "httpupgrade", Path: "/up", Host: "example.com"},
+			want: &TransportConfig{Type: "httpupgrade", Path: "/up", Host: "example.com"},
+		},
+		{name: "quic", node: models.Node{Network: "quic"}, want: &TransportConfig{Type: "quic"}},
+		{name: "kcp", node: models.Node{Network: "kcp"}, wantErr: true},
+		{name: "xhttp", node: models.Node{Network: "xhttp"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildTransportConfig(&tt.node)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildTransportConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildTransportConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
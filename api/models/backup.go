@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// BackupSchemaVersion 备份文件的数据结构版本，备份内容变化时递增
+const BackupSchemaVersion = 1
+
+// 恢复备份的方式
+const (
+	RestoreModeMerge   = "merge"   // 按 ID 覆盖或新增，保留备份中没有的数据
+	RestoreModeReplace = "replace" // 先清空再导入
+)
+
+// Backup 数据库备份，用于在不同设备之间迁移配置
+// 用户和配置版本历史不包含在备份中
+type Backup struct {
+	SchemaVersion int            `json:"schema_version"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Nodes         []Node         `json:"nodes"`
+	NodeGroups    []NodeGroup    `json:"node_groups"`
+	PolicyGroups  []PolicyGroup  `json:"policy_groups"`
+	Rules         []Rule         `json:"rules"`
+	RuleSets      []RuleSet      `json:"rule_sets"`
+	DNSRules      []DNSRule      `json:"dns_rules"`
+	DNSServers    []DNSServer    `json:"dns_servers"`
+	DNSSettings   *DNSSettings   `json:"dns_settings"`
+	Subscriptions []Subscription `json:"subscriptions"`
+	Settings      *Settings      `json:"settings"`
+}
+
+// Validate 验证备份版本
+func (b *Backup) Validate() error {
+	if b.SchemaVersion == 0 {
+		return fmt.Errorf("backup schema version is required")
+	}
+	if b.SchemaVersion > BackupSchemaVersion {
+		return fmt.Errorf("unsupported backup schema version %d, latest supported is %d", b.SchemaVersion, BackupSchemaVersion)
+	}
+	return nil
+}
+
+// ValidateData 验证备份中的每条数据，与新增或修改时的验证相同
+func (b *Backup) ValidateData() error {
+	for i := range b.Nodes {
+		if err := b.Nodes[i].Validate(); err != nil {
+			return fmt.Errorf("node %s: %w", b.Nodes[i].Name, err)
+		}
+	}
+	for i := range b.NodeGroups {
+		if err := b.NodeGroups[i].Validate(); err != nil {
+			return fmt.Errorf("node group %s: %w", b.NodeGroups[i].Name, err)
+		}
+	}
+	for i := range b.PolicyGroups {
+		if err := b.PolicyGroups[i].Validate(); err != nil {
+			return fmt.Errorf("policy group %s: %w", b.PolicyGroups[i].Name, err)
+		}
+	}
+	for i := range b.Rules {
+		if err := b.Rules[i].Validate(); err != nil {
+			return fmt.Errorf("rule %s: %w", b.Rules[i].Name, err)
+		}
+	}
+	for i := range b.DNSRules {
+		if err := b.DNSRules[i].Validate(); err != nil {
+			return fmt.Errorf("dns rule %s: %w", b.DNSRules[i].Value, err)
+		}
+	}
+	for i := range b.DNSServers {
+		if err := b.DNSServers[i].Validate(); err != nil {
+			return fmt.Errorf("dns server %s: %w", b.DNSServers[i].Tag, err)
+		}
+	}
+	for i := range b.Subscriptions {
+		if err := b.Subscriptions[i].Validate(); err != nil {
+			return fmt.Errorf("subscription %s: %w", b.Subscriptions[i].Name, err)
+		}
+	}
+	if b.DNSSettings != nil {
+		if err := b.DNSSettings.Validate(); err != nil {
+			return fmt.Errorf("dns settings: %w", err)
+		}
+	}
+	if b.Settings != nil {
+		if err := b.Settings.ValidateInbound(); err != nil {
+			return fmt.Errorf("settings: %w", err)
+		}
+		if err := b.Settings.ValidateURLTest(); err != nil {
+			return fmt.Errorf("settings: %w", err)
+		}
+		if err := b.Settings.ValidateConfigHistory(); err != nil {
+			return fmt.Errorf("settings: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package models
+
+import "testing"
+
+func TestBackupValidateData(t *testing.T) {
+	tests := []struct {
+		name    string
+		backup  Backup
+		wantErr bool
+	}{
+		{name: "empty", backup: Backup{}},
+		{name: "valid", backup: Backup{
+			NodeGroups:   []NodeGroup{{Name: "香港", Mode: NodeGroupModeFallback}},
+			PolicyGroups: []PolicyGroup{{Name: "流媒体"}},
+			DNSServers:   []DNSServer{{Tag: "cf", Address: "1.1.1.1"}},
+			Settings:     &Settings{},
+		}},
+		{name: "node group mode", backup: Backup{NodeGroups: []NodeGroup{{Name: "香港", Mode: "load-balance"}}}, wantErr: true},
+		{name: "reserved dns server tag", backup: Backup{DNSServers: []DNSServer{{Tag: "fakeip", Address: "1.1.1.1"}}}, wantErr: true},
+		{name: "policy group", backup: Backup{PolicyGroups: []PolicyGroup{{Name: "流媒体", Outbounds: StringArray{"a", "a"}}}}, wantErr: true},
+		{name: "inbound settings", backup: Backup{Settings: &Settings{DNSInbound: "sometimes"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := tt.backup.ValidateData()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidateData() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// 生成配置的触发来源，其他修改使用对应 API 资源名称 (如 nodes、rules)
+const (
+	ConfigTriggerSettings     = "settings"
+	ConfigTriggerSubscription = "subscription"
+	ConfigTriggerManual       = "manual"
+	ConfigTriggerRollback     = "rollback"
+)
+
+// ConfigVersion 已应用的 sing-box 配置文件版本
+type ConfigVersion struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	Trigger   string    `json:"trigger"`
+	User      string    `json:"user"`    // 触发修改的用户，自动更新时为空
+	Hash      string    `json:"hash"`    // 配置内容的 SHA-256
+	Size      int       `json:"size"`    // 配置内容字节数
+	Checked   bool      `json:"checked"` // 是否通过 sing-box check，sing-box 不存在时未检查
+	Content   string    `json:"content,omitempty" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+}
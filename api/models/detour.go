@@ -0,0 +1,116 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResolveDetour 查找代理链目标，依次按节点组 ID、节点 ID、节点组名称或标签、节点名称匹配
+func ResolveDetour(detour string, nodes []Node, groups []NodeGroup) (*Node, *NodeGroup) {
+	for i := range groups {
+		if groups[i].ID == detour {
+			return nil, &groups[i]
+		}
+	}
+	for i := range nodes {
+		if nodes[i].ID == detour {
+			return &nodes[i], nil
+		}
+	}
+	for i := range groups {
+		if groups[i].Name == detour || (groups[i].Tag != "" && groups[i].Tag == detour) {
+			return nil, &groups[i]
+		}
+	}
+	for i := range nodes {
+		if nodes[i].Name == detour {
+			return &nodes[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// CheckDetours 检查节点和节点组的代理链目标是否存在，以及代理链是否形成循环
+// 节点经由节点组时，流量会进入组内的任意节点，因此节点组指向其包含的所有节点
+func CheckDetours(nodes []Node, groups []NodeGroup) error {
+	for i := range groups {
+		if detour := groups[i].Detour; detour != "" {
+			if node, group := ResolveDetour(detour, nodes, groups); node == nil && group == nil {
+				return fmt.Errorf("node group %s: unknown detour %s", groups[i].Name, detour)
+			}
+		}
+	}
+
+	edges := make(map[string][]string)
+	names := make(map[string]string)
+	for i := range nodes {
+		node := &nodes[i]
+		key := "node:" + node.ID
+		names[key] = node.Name
+
+		detour := EffectiveOutboundOptions(node, groups).Detour
+		if detour == "" {
+			continue
+		}
+		target, group := ResolveDetour(detour, nodes, groups)
+		switch {
+		case target != nil:
+			edges[key] = append(edges[key], "node:"+target.ID)
+		case group != nil:
+			edges[key] = append(edges[key], "group:"+group.ID)
+		default:
+			return fmt.Errorf("node %s: unknown detour %s", node.Name, detour)
+		}
+	}
+	for i := range groups {
+		group := &groups[i]
+		key := "group:" + group.ID
+		names[key] = group.Name
+		for j := range nodes {
+			if group.Contains(&nodes[j]) {
+				edges[key] = append(edges[key], "node:"+nodes[j].ID)
+			}
+		}
+	}
+
+	// 深度优先搜索查找环
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(key string) error
+	visit = func(key string) error {
+		switch state[key] {
+		case visiting:
+			var cycle []string
+			for i := len(path) - 1; i >= 0; i-- {
+				cycle = append([]string{names[path[i]]}, cycle...)
+				if path[i] == key {
+					break
+				}
+			}
+			cycle = append(cycle, names[key])
+			return fmt.Errorf("detour cycle detected: %s", strings.Join(cycle, " -> "))
+		case visited:
+			return nil
+		}
+		state[key] = visiting
+		path = append(path, key)
+		for _, next := range edges[key] {
+			if err := visit(next); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[key] = visited
+		return nil
+	}
+	for i := range nodes {
+		if err := visit("node:" + nodes[i].ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
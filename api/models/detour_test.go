@@ -0,0 +1,122 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveDetour(t *testing.T) {
+	nodes := []Node{
+		{ID: "n1", Name: "香港 01"},
+		{ID: "n2", Name: "Relay"},
+	}
+	groups := []NodeGroup{
+		{ID: "g1", Name: "香港 🇭🇰", Tag: "HK"},
+		// 节点组名称与节点 ID 相同时节点组 ID 优先，名称匹配排在 ID 之后
+		{ID: "Relay", Name: "n1"},
+	}
+
+	tests := []struct {
+		detour    string
+		wantNode  string
+		wantGroup string
+	}{
+		{detour: "g1", wantGroup: "g1"},
+		{detour: "n1", wantNode: "n1"},
+		{detour: "香港 🇭🇰", wantGroup: "g1"},
+		{detour: "HK", wantGroup: "g1"},
+		{detour: "香港 01", wantNode: "n1"},
+		{detour: "Relay", wantGroup: "Relay"},
+		{detour: "missing"},
+	}
+	for _, tt := range tests {
+		node, group := ResolveDetour(tt.detour, nodes, groups)
+		var gotNode, gotGroup string
+		if node != nil {
+			gotNode = node.ID
+		}
+		if group != nil {
+			gotGroup = group.ID
+		}
+		if gotNode != tt.wantNode || gotGroup != tt.wantGroup {
+			t.Errorf("ResolveDetour(%q) = node %q, group %q; want node %q, group %q",
+				tt.detour, gotNode, gotGroup, tt.wantNode, tt.wantGroup)
+		}
+	}
+}
+
+func TestCheckDetours(t *testing.T) {
+	withDetour := func(node Node, detour string) Node {
+		node.Detour = detour
+		return node
+	}
+	hk := Node{ID: "hk", Name: "HK 01"}
+	jp := Node{ID: "jp", Name: "JP 01"}
+	relayGroup := NodeGroup{ID: "relay", Name: "中转", Active: true, IncludePatterns: StringArray{"JP"}}
+
+	tests := []struct {
+		name    string
+		nodes   []Node
+		groups  []NodeGroup
+		wantErr string
+	}{
+		{
+			name:  "no detours",
+			nodes: []Node{hk, jp},
+		},
+		{
+			name:  "node chain",
+			nodes: []Node{withDetour(hk, "jp"), jp},
+		},
+		{
+			name:   "group target",
+			nodes:  []Node{withDetour(hk, "中转"), jp},
+			groups: []NodeGroup{relayGroup},
+		},
+		{
+			name:    "self reference",
+			nodes:   []Node{withDetour(hk, "hk")},
+			wantErr: "detour cycle detected: HK 01 -> HK 01",
+		},
+		{
+			name:    "node cycle",
+			nodes:   []Node{withDetour(hk, "jp"), withDetour(jp, "HK 01")},
+			wantErr: "detour cycle detected",
+		},
+		{
+			name:    "cycle through group",
+			nodes:   []Node{withDetour(hk, "relay"), withDetour(jp, "hk")},
+			groups:  []NodeGroup{relayGroup},
+			wantErr: "detour cycle detected",
+		},
+		{
+			name:    "unknown node target",
+			nodes:   []Node{withDetour(hk, "deleted")},
+			wantErr: "node HK 01: unknown detour deleted",
+		},
+		{
+			name:  "unknown group target",
+			nodes: []Node{hk},
+			groups: []NodeGroup{func() NodeGroup {
+				g := relayGroup
+				g.Detour = "deleted"
+				return g
+			}()},
+			wantErr: "node group 中转: unknown detour deleted",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckDetours(tt.nodes, tt.groups)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CheckDetours() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("CheckDetours() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
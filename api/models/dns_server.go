@@ -0,0 +1,60 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// 生成器内置的 DNS 服务器标签，自定义服务器不能使用
+var reservedDNSServerTags = map[string]bool{
+	"alidns":    true,
+	"google":    true,
+	"dns-block": true,
+	"dns-local": true,
+	"fakeip":    true,
+}
+
+// DNSServer 自定义 DNS 上游服务器
+type DNSServer struct {
+	ID              string    `json:"id" gorm:"primaryKey"`
+	Tag             string    `json:"tag" gorm:"uniqueIndex;not null"`
+	Address         string    `json:"address" gorm:"not null"`
+	Type            string    `json:"type"`             // udp, tcp, tls, https, quic 或 h3
+	AddressResolver string    `json:"address_resolver"` // 解析服务器域名使用的 DNS 服务器标签
+	Strategy        string    `json:"strategy"`
+	Detour          string    `json:"detour"` // 出站标签
+	ClientSubnet    string    `json:"client_subnet"`
+	Enabled         bool      `json:"enabled" gorm:"default:true"`
+	CreatedAt       time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// Validate 验证 DNS 服务器
+func (s *DNSServer) Validate() error {
+	if s.Tag == "" {
+		return fmt.Errorf("dns server tag is required")
+	}
+	if reservedDNSServerTags[s.Tag] {
+		return fmt.Errorf("dns server tag %s is reserved", s.Tag)
+	}
+	if s.Address == "" {
+		return fmt.Errorf("dns server address is required")
+	}
+	if !isValidDNSType(s.Type) {
+		return fmt.Errorf("invalid dns server type: %s", s.Type)
+	}
+	switch s.Strategy {
+	case "", "prefer_ipv4", "prefer_ipv6", "ipv4_only", "ipv6_only":
+	default:
+		return fmt.Errorf("invalid dns server strategy: %s", s.Strategy)
+	}
+	if s.AddressResolver == s.Tag {
+		return fmt.Errorf("dns server %s cannot resolve itself", s.Tag)
+	}
+	return nil
+}
+
+// IsBuiltinDNSServerTag 判断是否为内置 DNS 服务器标签
+func IsBuiltinDNSServerTag(tag string) bool {
+	return reservedDNSServerTags[tag]
+}
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"fmt"
+	"net"
+)
+
 // DNSSettings DNS 设置
 type DNSSettings struct {
 	ID               string `json:"id" gorm:"primaryKey"`
@@ -13,12 +18,21 @@ type DNSSettings struct {
 	AdBlockRuleCount int    `json:"ad_block_rule_count" gorm:"default:0"`
 	LastUpdate       int64  `json:"last_update" gorm:"default:0"`
 
+	// 广告拦截白名单，命中的域名不会被拦截
+	AdBlockAllowlist StringArray `json:"ad_block_allowlist" gorm:"type:json"`
+
+	// FakeIP 设置，过滤列表中的域名始终返回真实地址
+	EnableFakeIP     bool        `json:"enable_fakeip"`
+	FakeIPInet4Range string      `json:"fakeip_inet4_range"`
+	FakeIPInet6Range string      `json:"fakeip_inet6_range"`
+	FakeIPFilter     StringArray `json:"fakeip_filter" gorm:"type:json"`
+
 	// DNS 服务器设置
 	Domestic         string `json:"domestic" gorm:"column:domestic"`
 	DomesticType     string `json:"domestic_type" gorm:"column:domestic_type"`
 	SingboxDNS       string `json:"singbox_dns" gorm:"column:singbox_dns"`
 	SingboxDNSType   string `json:"singbox_dns_type" gorm:"column:singbox_dns_type"`
-	EDNSClientSubnet string `json:"edns_client_subnet" gorm:"column:edns_client_subnet"`
+	EDNSClientSubnet string `json:"edns_client_subnet" gorm:"column:edns_client_subnet"` // 启用 FakeIP 时 A/AAAA 查询返回 FakeIP，不使用 ECS
 }
 
 // Validate 验证 DNS 设置
@@ -32,6 +46,49 @@ func (s *DNSSettings) Validate() error {
 	if s.Strategy == "" {
 		s.Strategy = "ipv4_only" // 默认使用 ipv4_only
 	}
+	switch s.Strategy {
+	case "prefer_ipv4", "prefer_ipv6", "ipv4_only", "ipv6_only":
+	default:
+		return fmt.Errorf("invalid dns strategy: %s", s.Strategy)
+	}
+
+	// 验证 DNS 服务器类型
+	if s.Domestic == "" {
+		s.Domestic = "223.5.5.5"
+	}
+	if s.SingboxDNS == "" {
+		s.SingboxDNS = "https://dns.google/dns-query"
+	}
+	if !isValidDNSType(s.DomesticType) {
+		return fmt.Errorf("invalid domestic dns type: %s", s.DomesticType)
+	}
+	if !isValidDNSType(s.SingboxDNSType) {
+		return fmt.Errorf("invalid singbox dns type: %s", s.SingboxDNSType)
+	}
+
+	// 验证 FakeIP 地址段
+	if s.FakeIPInet4Range == "" {
+		s.FakeIPInet4Range = "198.18.0.0/15"
+	}
+	if s.FakeIPInet6Range == "" {
+		s.FakeIPInet6Range = "fc00::/18"
+	}
+	if ip, _, err := net.ParseCIDR(s.FakeIPInet4Range); err != nil || ip.To4() == nil {
+		return fmt.Errorf("invalid fakeip inet4 range: %s", s.FakeIPInet4Range)
+	}
+	if ip, _, err := net.ParseCIDR(s.FakeIPInet6Range); err != nil || ip.To4() != nil {
+		return fmt.Errorf("invalid fakeip inet6 range: %s", s.FakeIPInet6Range)
+	}
 
 	return nil
 }
+
+// isValidDNSType 检查 DNS 服务器类型是否受支持，空值表示沿用地址中的协议
+func isValidDNSType(dnsType string) bool {
+	switch dnsType {
+	case "", "udp", "tcp", "tls", "dot", "https", "doh", "quic", "doq", "h3", "doh3":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// Migration 已执行的一次性数据迁移
+type Migration struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
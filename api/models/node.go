@@ -4,6 +4,8 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -52,6 +54,10 @@ type Node struct {
 	PublicKey        string      `json:"public_key"`
 	ShortID          string      `json:"short_id"`
 	SpiderX          string      `json:"spider_x"`
+	TLSMinVersion    string      `json:"tls_min_version"`
+	TLSMaxVersion    string      `json:"tls_max_version"`
+	ECH              bool        `json:"ech"`
+	ECHConfig        string      `json:"ech_config"` // PEM 格式的 ECH 配置，为空时通过 DNS 获取
 	Flow             string      `json:"flow"`
 	Version          string      `json:"version"`
 	Method           string      `json:"method"`
@@ -61,6 +67,8 @@ type Node struct {
 	Down             string      `json:"down"`
 	ServiceName      string      `json:"service_name"`
 	Obfs             string      `json:"obfs"`
+	ObfsPassword     string      `json:"obfs_password"`
+	UDPRelayMode     string      `json:"udp_relay_mode"`
 	UDP              bool        `json:"udp"`
 	Plugin           string      `json:"plugin"`
 	PluginOpts       string      `json:"plugin_opts"`
@@ -73,4 +81,105 @@ type Node struct {
 	CreatedAt        time.Time   `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt        time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
 	CheckedAt        time.Time   `json:"checked_at"`
+
+	// 多路复用、TCP Fast Open 和 UDP over TCP 设置
+	OutboundOptions
+}
+
+// DefaultUTLSFingerprint REALITY 节点未指定指纹时使用的 uTLS 指纹
+const DefaultUTLSFingerprint = "chrome"
+
+// sing-box 支持的 uTLS 指纹
+var utlsFingerprints = map[string]bool{
+	"chrome":     true,
+	"firefox":    true,
+	"edge":       true,
+	"safari":     true,
+	"360":        true,
+	"qq":         true,
+	"ios":        true,
+	"android":    true,
+	"random":     true,
+	"randomized": true,
+}
+
+// Validate 验证节点的 TLS 和出站设置
+func (n *Node) Validate() error {
+	if err := n.ValidateTLS(); err != nil {
+		return err
+	}
+	if err := n.ValidateTransport(); err != nil {
+		return err
+	}
+	if err := n.ValidatePlugin(); err != nil {
+		return err
+	}
+	return n.OutboundOptions.Validate()
+}
+
+// ValidateTLS 验证节点的 TLS 相关设置
+func (n *Node) ValidateTLS() error {
+	if n.Fingerprint != "" && !utlsFingerprints[n.Fingerprint] {
+		return fmt.Errorf("invalid utls fingerprint: %s", n.Fingerprint)
+	}
+	if !isValidTLSVersion(n.TLSMinVersion) {
+		return fmt.Errorf("invalid tls min version: %s", n.TLSMinVersion)
+	}
+	if !isValidTLSVersion(n.TLSMaxVersion) {
+		return fmt.Errorf("invalid tls max version: %s", n.TLSMaxVersion)
+	}
+	if n.TLSMinVersion != "" && n.TLSMaxVersion != "" && n.TLSMinVersion > n.TLSMaxVersion {
+		return fmt.Errorf("tls min version %s is greater than max version %s", n.TLSMinVersion, n.TLSMaxVersion)
+	}
+	if n.ECHConfig != "" && !strings.Contains(n.ECHConfig, "BEGIN ECH CONFIGS") {
+		return fmt.Errorf("ech config must be in PEM format")
+	}
+	return nil
+}
+
+// 支持的 V2Ray 传输方式，包括订阅中常见的别名
+var transportNetworks = map[string]bool{
+	"":            true,
+	"tcp":         true,
+	"raw":         true,
+	"ws":          true,
+	"websocket":   true,
+	"grpc":        true,
+	"gun":         true,
+	"h2":          true,
+	"http":        true,
+	"httpupgrade": true,
+	"quic":        true,
+}
+
+// ValidateTransport 验证 vmess、vless、trojan 节点的传输方式
+// 其他协议的 network 表示 tcp/udp 网络，不在此验证
+func (n *Node) ValidateTransport() error {
+	switch n.Type {
+	case "vmess", "vless", "trojan":
+		if !transportNetworks[strings.ToLower(n.Network)] {
+			return fmt.Errorf("unsupported transport: %s", n.Network)
+		}
+	}
+	return nil
+}
+
+// ValidatePlugin 验证 Shadowsocks 节点的插件设置
+func (n *Node) ValidatePlugin() error {
+	switch n.Type {
+	case "shadowsocks", "ss":
+		if _, _, err := NormalizeSSPluginString(n.Plugin, n.PluginOpts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isValidTLSVersion 判断是否为 sing-box 支持的 TLS 版本
+func isValidTLSVersion(version string) bool {
+	switch version {
+	case "", "1.0", "1.1", "1.2", "1.3":
+		return true
+	}
+	return false
 }
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"net/url"
 	"regexp"
 	"strings"
 	"time"
@@ -12,7 +14,10 @@ type NodeGroup struct {
 	Name            string      `json:"name" gorm:"not null"`
 	Description     string      `json:"description"`
 	Tag             string      `json:"tag"`
-	Mode            string      `json:"mode" gorm:"default:select"`
+	Mode            string      `json:"mode" gorm:"default:select"` // select、urltest 或 fallback
+	TestURL         string      `json:"test_url"`                   // 测速地址
+	TestInterval    string      `json:"test_interval"`              // 测速间隔，如 3m
+	Tolerance       int         `json:"tolerance"`                  // 延迟容差 (毫秒)
 	Active          bool        `json:"active" gorm:"default:true"`
 	IncludePatterns StringArray `json:"include_patterns" gorm:"type:json"`
 	ExcludePatterns StringArray `json:"exclude_patterns" gorm:"type:json"`
@@ -20,6 +25,62 @@ type NodeGroup struct {
 	NodeCount       int         `json:"node_count" gorm:"default:0"`
 	CreatedAt       time.Time   `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt       time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// 多路复用、TCP Fast Open 和 UDP over TCP 设置
+	OutboundOptions
+}
+
+// 节点组模式
+const (
+	NodeGroupModeSelect   = "select"
+	NodeGroupModeURLTest  = "urltest"
+	NodeGroupModeFallback = "fallback"
+)
+
+// IsSupportedNodeGroupMode 判断节点组模式是否可以生成对应的 sing-box 出站
+func IsSupportedNodeGroupMode(mode string) bool {
+	switch mode {
+	case "", NodeGroupModeSelect, NodeGroupModeURLTest, NodeGroupModeFallback:
+		return true
+	}
+	return false
+}
+
+// Validate 验证节点组设置
+func (g *NodeGroup) Validate() error {
+	switch g.Mode {
+	case "", NodeGroupModeSelect, NodeGroupModeURLTest, NodeGroupModeFallback:
+	default:
+		return fmt.Errorf("invalid node group mode: %s", g.Mode)
+	}
+	// 未设置的测速选项使用全局设置
+	if err := validateURLTest(g.TestURL, g.TestInterval, g.Tolerance); err != nil {
+		return err
+	}
+	return g.OutboundOptions.Validate()
+}
+
+// validateURLTest 验证测速地址、间隔和容差，空值表示使用默认值
+func validateURLTest(testURL, interval string, tolerance int) error {
+	if testURL != "" {
+		if u, err := url.Parse(testURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid test url: %s", testURL)
+		}
+	}
+	if interval != "" {
+		if d, err := time.ParseDuration(interval); err != nil || d <= 0 {
+			return fmt.Errorf("invalid test interval: %s", interval)
+		}
+	}
+	if tolerance < 0 || tolerance > 65535 {
+		return fmt.Errorf("tolerance must be between 0 and 65535")
+	}
+	return nil
+}
+
+// Contains 判断节点是否属于该节点组，"全部"分组包含所有节点
+func (g *NodeGroup) Contains(node *Node) bool {
+	return g.Name == "全部" || g.MatchNode(node)
 }
 
 // MatchNode checks if a node matches the group's patterns
@@ -0,0 +1,28 @@
+package models
+
+import "testing"
+
+func TestValidateTransport(t *testing.T) {
+	tests := []struct {
+		nodeType string
+		network  string
+		wantErr  bool
+	}{
+		{nodeType: "vmess", network: ""},
+		{nodeType: "vmess", network: "ws"},
+		{nodeType: "vless", network: "GRPC"},
+		{nodeType: "trojan", network: "httpupgrade"},
+		{nodeType: "vmess", network: "kcp", wantErr: true},
+		{nodeType: "vless", network: "xhttp", wantErr: true},
+		// 其他协议的 network 为 tcp/udp 网络
+		{nodeType: "ss", network: "udp"},
+		{nodeType: "hysteria2", network: "kcp"},
+	}
+	for _, tt := range tests {
+		node := Node{Type: tt.nodeType, Network: tt.network}
+		err := node.ValidateTransport()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateTransport(%s, %q) error = %v, wantErr %v", tt.nodeType, tt.network, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package models
+
+import "fmt"
+
+// MultiplexOptions 出站多路复用设置
+type MultiplexOptions struct {
+	Enabled        bool   `json:"enabled"`
+	Protocol       string `json:"protocol"` // smux, yamux 或 h2mux，为空时使用 h2mux
+	MaxConnections int    `json:"max_connections"`
+	MinStreams     int    `json:"min_streams"`
+	MaxStreams     int    `json:"max_streams"`
+	Padding        bool   `json:"padding"`
+}
+
+// Validate 验证多路复用设置
+func (m *MultiplexOptions) Validate() error {
+	if m == nil {
+		return nil
+	}
+	switch m.Protocol {
+	case "", "smux", "yamux", "h2mux":
+	default:
+		return fmt.Errorf("invalid multiplex protocol: %s", m.Protocol)
+	}
+	if m.MaxConnections < 0 || m.MinStreams < 0 || m.MaxStreams < 0 {
+		return fmt.Errorf("multiplex connections and streams must not be negative")
+	}
+	// sing-box 中 max_streams 与 max_connections、min_streams 互斥
+	if m.MaxStreams > 0 && (m.MaxConnections > 0 || m.MinStreams > 0) {
+		return fmt.Errorf("multiplex max_streams conflicts with max_connections and min_streams")
+	}
+	return nil
+}
+
+// OutboundOptions 节点和节点组共用的出站设置，未设置的项使用上一级的设置
+type OutboundOptions struct {
+	Multiplex   *MultiplexOptions `json:"multiplex" gorm:"serializer:json"`
+	TCPFastOpen *bool             `json:"tcp_fast_open"`
+	UDPOverTCP  *bool             `json:"udp_over_tcp"`
+	Detour      string            `json:"detour"` // 代理链目标，可以是节点或节点组的 ID 或名称
+}
+
+// Validate 验证出站设置
+func (o *OutboundOptions) Validate() error {
+	return o.Multiplex.Validate()
+}
+
+// MergeOutboundOptions 合并出站设置，node 中已设置的项优先
+func MergeOutboundOptions(node, group OutboundOptions) OutboundOptions {
+	merged := group
+	if node.Multiplex != nil {
+		merged.Multiplex = node.Multiplex
+	}
+	if node.TCPFastOpen != nil {
+		merged.TCPFastOpen = node.TCPFastOpen
+	}
+	if node.UDPOverTCP != nil {
+		merged.UDPOverTCP = node.UDPOverTCP
+	}
+	if node.Detour != "" {
+		merged.Detour = node.Detour
+	}
+	return merged
+}
+
+// EffectiveOutboundOptions 计算节点最终使用的出站设置
+// 节点未设置的项依次使用所在的已启用节点组中第一个设置了该项的节点组
+func EffectiveOutboundOptions(node *Node, groups []NodeGroup) OutboundOptions {
+	options := node.OutboundOptions
+	for i := range groups {
+		if groups[i].Active && groups[i].Contains(node) {
+			options = MergeOutboundOptions(options, groups[i].OutboundOptions)
+		}
+	}
+	return options
+}
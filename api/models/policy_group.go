@@ -0,0 +1,64 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// 策略组中的内置候选出站
+const (
+	PolicyOutboundProxy  = "节点选择"
+	PolicyOutboundDirect = "direct"
+	PolicyOutboundBlock  = "block"
+)
+
+// PolicyGroup 策略组，规则和规则集可以将其作为出站
+type PolicyGroup struct {
+	ID        string      `json:"id" gorm:"primaryKey"`
+	Name      string      `json:"name" gorm:"uniqueIndex;not null"`
+	Icon      string      `json:"icon"`
+	Outbounds StringArray `json:"outbounds" gorm:"type:json"` // 候选出站，为空时使用节点选择、所有节点组、直连和拦截
+	Default   string      `json:"default"`
+	Priority  int         `json:"priority"` // 在配置中的顺序，数值越小越靠前
+	CreatedAt time.Time   `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// Tag 返回策略组在配置中的出站标签
+func (p *PolicyGroup) Tag() string {
+	if p.Icon == "" {
+		return p.Name
+	}
+	return p.Icon + " " + p.Name
+}
+
+// Validate 验证策略组
+func (p *PolicyGroup) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("policy group name is required")
+	}
+	seen := make(map[string]bool, len(p.Outbounds))
+	for _, outbound := range p.Outbounds {
+		if outbound == "" {
+			return fmt.Errorf("policy group outbound must not be empty")
+		}
+		if seen[outbound] {
+			return fmt.Errorf("duplicate policy group outbound: %s", outbound)
+		}
+		seen[outbound] = true
+	}
+	if p.Default != "" && len(p.Outbounds) > 0 && !seen[p.Default] {
+		return fmt.Errorf("default outbound %s is not in policy group outbounds", p.Default)
+	}
+	return nil
+}
+
+// DefaultPolicyGroups 返回默认策略组
+func DefaultPolicyGroups() []PolicyGroup {
+	return []PolicyGroup{
+		{ID: "streaming", Name: "流媒体", Icon: "🎬", Default: PolicyOutboundProxy, Priority: 1},
+		{ID: "social", Name: "社交媒体", Icon: "💬", Default: PolicyOutboundProxy, Priority: 2},
+		{ID: "google", Name: "谷歌服务", Icon: "🔍", Default: PolicyOutboundProxy, Priority: 3},
+		{ID: "dev", Name: "开发服务", Icon: "💻", Default: PolicyOutboundProxy, Priority: 4},
+	}
+}
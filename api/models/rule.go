@@ -24,7 +24,7 @@ type DNSRule struct {
 	ID          string    `json:"id" gorm:"primaryKey"`
 	Type        string    `json:"type" gorm:"not null"` // domain 或 ip
 	Value       string    `json:"value" gorm:"not null"`
-	Action      string    `json:"action" gorm:"not null"` // direct, remote 或 block
+	Action      string    `json:"action" gorm:"not null"` // direct, remote, block 或 DNS 服务器标签
 	Description string    `json:"description"`
 	Enabled     bool      `json:"enabled" gorm:"default:true"`
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
@@ -50,8 +50,8 @@ func (r *DNSRule) Validate() error {
 	if r.Type != "domain" && r.Type != "ip" {
 		return fmt.Errorf("invalid rule type: %s", r.Type)
 	}
-	if r.Action != "direct" && r.Action != "remote" && r.Action != "block" {
-		return fmt.Errorf("invalid rule action: %s", r.Action)
+	if r.Action == "" {
+		return fmt.Errorf("rule action is required")
 	}
 	if r.Value == "" {
 		return fmt.Errorf("rule value is required")
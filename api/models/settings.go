@@ -2,8 +2,35 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"net"
+	"time"
 )
 
+// 入站默认值
+const (
+	DefaultProxyPort       = 7890
+	DefaultRedirectPort    = 7892
+	DefaultTProxyPort      = 7893
+	DefaultDNSPort         = 5353
+	DefaultListenAddress   = "::"
+	DefaultTunInet4Address = "172.19.0.1/30"
+	DefaultTunInet6Address = "fdfe:dcba:9876::1/126"
+	DefaultTunStack        = "system"
+	DefaultRoutingMark     = 1
+	DefaultRouteTable      = 100
+)
+
+// 自动测速默认值
+const (
+	DefaultURLTestURL       = "http://www.gstatic.com/generate_204"
+	DefaultURLTestInterval  = "300s"
+	DefaultURLTestTolerance = 50
+)
+
+// DefaultConfigHistoryLimit 默认保留的配置版本数
+const DefaultConfigHistoryLimit = 50
+
 // Settings represents system settings
 type Settings struct {
 	ID               string `json:"id" gorm:"primaryKey"`
@@ -17,13 +44,152 @@ type Settings struct {
 	LogLevel         string `json:"log_level"`
 	EnableAutoUpdate bool   `json:"enable_auto_update"`
 	TunInterface     string `json:"tun_interface"` // TUN 接口名称
+	EnableIPv6       bool   `json:"enable_ipv6"`   // 启用 IPv6 代理
+	RedirectPort     int    `json:"redirect_port"`
+	TProxyPort       int    `json:"tproxy_port"`
+	ListenAddress    string `json:"listen_address"`
+	TunInet4Address  string `json:"tun_inet4_address"`
+	TunInet6Address  string `json:"tun_inet6_address"`
+	TunStack         string `json:"tun_stack"`    // system, gvisor 或 mixed
+	RoutingMark      int    `json:"routing_mark"` // TProxy 使用的 fwmark
+	RouteTable       int    `json:"route_table"`  // TProxy 使用的策略路由表
+	DNSInbound       string `json:"dns_inbound"`  // auto, enabled 或 disabled
+	URLTestURL       string `json:"url_test_url"` // 节点组默认测速地址
+	URLTestInterval  string `json:"url_test_interval"`
+	URLTestTolerance int    `json:"url_test_tolerance"`
+	ConfigHistory    int    `json:"config_history"` // 保留的配置版本数
 	UpdatedAt        int64  `json:"updated_at"`
 	Dashboard        string `json:"dashboard" gorm:"type:json"`
 	SingboxMode      string `json:"singbox_mode" gorm:"type:json"`
 	InboundMode      string `json:"inbound_mode" gorm:"type:json"`
+	InboundOptions   string `json:"inbound_options" gorm:"type:json"`
 	CreatedAt        int64  `json:"created_at"`
 }
 
+// ValidateInbound 补全并验证入站设置
+func (s *Settings) ValidateInbound() error {
+	if s.ProxyPort == 0 {
+		s.ProxyPort = DefaultProxyPort
+	}
+	if s.RedirectPort == 0 {
+		s.RedirectPort = DefaultRedirectPort
+	}
+	if s.TProxyPort == 0 {
+		s.TProxyPort = DefaultTProxyPort
+	}
+	if s.DNSPort == 0 {
+		s.DNSPort = DefaultDNSPort
+	}
+	if s.ListenAddress == "" {
+		s.ListenAddress = DefaultListenAddress
+	}
+	if s.TunInet4Address == "" {
+		s.TunInet4Address = DefaultTunInet4Address
+	}
+	if s.TunInet6Address == "" {
+		s.TunInet6Address = DefaultTunInet6Address
+	}
+	if s.TunStack == "" {
+		s.TunStack = DefaultTunStack
+	}
+	if s.RoutingMark == 0 {
+		s.RoutingMark = DefaultRoutingMark
+	}
+	if s.RouteTable == 0 {
+		s.RouteTable = DefaultRouteTable
+	}
+	if s.DNSInbound == "" {
+		s.DNSInbound = "auto"
+	}
+
+	// 验证端口，各入站端口不能重复
+	ports := map[string]int{
+		"proxy_port":    s.ProxyPort,
+		"redirect_port": s.RedirectPort,
+		"tproxy_port":   s.TProxyPort,
+		"dns_port":      s.DNSPort,
+	}
+	used := make(map[int]string, len(ports))
+	for _, name := range []string{"proxy_port", "redirect_port", "tproxy_port", "dns_port"} {
+		port := ports[name]
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("invalid %s: %d", name, port)
+		}
+		if other, ok := used[port]; ok {
+			return fmt.Errorf("%s conflicts with %s: %d", name, other, port)
+		}
+		used[port] = name
+	}
+
+	if net.ParseIP(s.ListenAddress) == nil {
+		return fmt.Errorf("invalid listen address: %s", s.ListenAddress)
+	}
+	if ip, _, err := net.ParseCIDR(s.TunInet4Address); err != nil || ip.To4() == nil {
+		return fmt.Errorf("invalid tun inet4 address: %s", s.TunInet4Address)
+	}
+	if ip, _, err := net.ParseCIDR(s.TunInet6Address); err != nil || ip.To4() != nil {
+		return fmt.Errorf("invalid tun inet6 address: %s", s.TunInet6Address)
+	}
+
+	switch s.TunStack {
+	case "system", "gvisor", "mixed":
+	default:
+		return fmt.Errorf("invalid tun stack: %s", s.TunStack)
+	}
+
+	if s.RoutingMark < 1 {
+		return fmt.Errorf("invalid routing mark: %d", s.RoutingMark)
+	}
+	// 253-255 为系统保留路由表
+	if s.RouteTable < 1 || (s.RouteTable >= 253 && s.RouteTable <= 255) {
+		return fmt.Errorf("invalid route table: %d", s.RouteTable)
+	}
+
+	switch s.DNSInbound {
+	case "auto", "enabled", "disabled":
+	default:
+		return fmt.Errorf("invalid dns inbound: %s", s.DNSInbound)
+	}
+	return nil
+}
+
+// ValidateURLTest 补全并验证节点组默认测速设置
+func (s *Settings) ValidateURLTest() error {
+	if s.URLTestURL == "" {
+		s.URLTestURL = DefaultURLTestURL
+	}
+	if s.URLTestInterval == "" {
+		s.URLTestInterval = DefaultURLTestInterval
+	}
+	if s.URLTestTolerance == 0 {
+		s.URLTestTolerance = DefaultURLTestTolerance
+	}
+	return validateURLTest(s.URLTestURL, s.URLTestInterval, s.URLTestTolerance)
+}
+
+// ValidateConfigHistory 补全并验证配置版本保留数
+func (s *Settings) ValidateConfigHistory() error {
+	if s.ConfigHistory == 0 {
+		s.ConfigHistory = DefaultConfigHistoryLimit
+	}
+	if s.ConfigHistory < 1 {
+		return fmt.Errorf("invalid config history limit: %d", s.ConfigHistory)
+	}
+	return nil
+}
+
+// DNSInboundEnabled 判断是否创建 dns-in 入站，auto 时仅在 redirect 模式下启用
+func (s *Settings) DNSInboundEnabled() bool {
+	switch s.DNSInbound {
+	case "enabled":
+		return true
+	case "disabled":
+		return false
+	default:
+		return s.GetInboundMode() == "redirect"
+	}
+}
+
 // GetDashboard 获取仪表盘设置
 func (s *Settings) GetDashboard() *DashboardSettings {
 	if s.Dashboard == "" {
@@ -134,6 +300,92 @@ func (s *Settings) SetInboundMode(mode string) error {
 	return nil
 }
 
+// InboundOptions 单个入站的嗅探与解析选项
+type InboundOptions struct {
+	Sniff                    *bool  `json:"sniff,omitempty"`                      // 默认开启
+	SniffOverrideDestination *bool  `json:"sniff_override_destination,omitempty"` // 默认开启
+	SniffTimeout             string `json:"sniff_timeout,omitempty"`              // 如 300ms，空值使用 sing-box 默认值
+	DomainStrategy           string `json:"domain_strategy,omitempty"`            // 空值跟随全局解析策略
+	UDPTimeout               int    `json:"udp_timeout,omitempty"`                // 秒
+}
+
+// SniffEnabled 是否开启嗅探
+func (o InboundOptions) SniffEnabled() bool {
+	return o.Sniff == nil || *o.Sniff
+}
+
+// OverrideDestination 是否使用嗅探到的域名覆盖目标地址
+func (o InboundOptions) OverrideDestination() bool {
+	return o.SniffOverrideDestination == nil || *o.SniffOverrideDestination
+}
+
+// Validate 验证入站选项
+func (o InboundOptions) Validate() error {
+	if o.SniffTimeout != "" {
+		if _, err := time.ParseDuration(o.SniffTimeout); err != nil {
+			return fmt.Errorf("invalid sniff timeout: %s", o.SniffTimeout)
+		}
+	}
+	switch o.DomainStrategy {
+	case "", "prefer_ipv4", "prefer_ipv6", "ipv4_only", "ipv6_only":
+	default:
+		return fmt.Errorf("invalid domain strategy: %s", o.DomainStrategy)
+	}
+	if o.UDPTimeout < 0 {
+		return fmt.Errorf("invalid udp timeout: %d", o.UDPTimeout)
+	}
+	return nil
+}
+
+// InboundOptionsSettings 各入站的选项，按入站类型区分
+type InboundOptionsSettings struct {
+	Tun      InboundOptions `json:"tun"`
+	Mixed    InboundOptions `json:"mixed"`
+	Redirect InboundOptions `json:"redirect"`
+	TProxy   InboundOptions `json:"tproxy"`
+}
+
+// Validate 验证所有入站选项
+func (o *InboundOptionsSettings) Validate() error {
+	for name, options := range map[string]InboundOptions{
+		"tun":      o.Tun,
+		"mixed":    o.Mixed,
+		"redirect": o.Redirect,
+		"tproxy":   o.TProxy,
+	} {
+		if err := options.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// GetInboundOptions 获取入站选项
+func (s *Settings) GetInboundOptions() *InboundOptionsSettings {
+	var options InboundOptionsSettings
+	if s.InboundOptions == "" {
+		return &options
+	}
+	if err := json.Unmarshal([]byte(s.InboundOptions), &options); err != nil {
+		// 如果解析失败，返回默认设置
+		return &InboundOptionsSettings{}
+	}
+	return &options
+}
+
+// SetInboundOptions 设置入站选项
+func (s *Settings) SetInboundOptions(options *InboundOptionsSettings) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	s.InboundOptions = string(data)
+	return nil
+}
+
 // GetDNSSettings 获取 DNS 设置
 func (s *Settings) GetDNSSettings() *DNSSettings {
 	// 从存储中获取 DNS 设置
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sing-box 支持的 Shadowsocks 插件
+const (
+	SSPluginObfsLocal   = "obfs-local"
+	SSPluginV2RayPlugin = "v2ray-plugin"
+)
+
+// ParseSSPluginString 解析 SIP002 格式的插件参数，如 "obfs-local;obfs=http;obfs-host=example.com"
+func ParseSSPluginString(value string) (string, map[string]string) {
+	parts := strings.Split(value, ";")
+	plugin := strings.TrimSpace(parts[0])
+	opts := parseSSPluginOpts(parts[1:])
+	return plugin, opts
+}
+
+// parseSSPluginOpts 解析 key=value 形式的插件选项，没有值的选项 (如 tls) 值为空字符串
+func parseSSPluginOpts(parts []string) map[string]string {
+	opts := make(map[string]string)
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		opts[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return opts
+}
+
+// NormalizeSSPlugin 将 SIP002 或 Clash 格式的插件名称和选项转换为 sing-box 使用的 plugin 和 plugin_opts
+func NormalizeSSPlugin(plugin string, opts map[string]string) (string, string, error) {
+	switch strings.ToLower(strings.TrimSpace(plugin)) {
+	case "":
+		return "", "", nil
+	case "obfs", "simple-obfs", "obfs-local":
+		// Clash 使用 mode/host，SIP002 使用 obfs/obfs-host
+		mode := firstSSPluginOpt(opts, "obfs", "mode")
+		if mode == "" {
+			mode = "http"
+		}
+		if mode != "http" && mode != "tls" {
+			return "", "", fmt.Errorf("unsupported obfs mode: %s", mode)
+		}
+		values := []string{"obfs=" + mode}
+		if host := firstSSPluginOpt(opts, "obfs-host", "host"); host != "" {
+			values = append(values, "obfs-host="+host)
+		}
+		return SSPluginObfsLocal, strings.Join(values, ";"), nil
+	case "v2ray-plugin":
+		mode := firstSSPluginOpt(opts, "mode")
+		if mode == "" {
+			mode = "websocket"
+		}
+		if mode != "websocket" {
+			return "", "", fmt.Errorf("unsupported v2ray-plugin mode: %s", mode)
+		}
+		values := []string{"mode=" + mode}
+		// tls 在 SIP002 中为无值选项，在 Clash 中为布尔值
+		if value, ok := opts["tls"]; ok && value != "false" {
+			values = append(values, "tls")
+		}
+		if host := firstSSPluginOpt(opts, "host"); host != "" {
+			values = append(values, "host="+host)
+		}
+		if path := firstSSPluginOpt(opts, "path"); path != "" {
+			values = append(values, "path="+path)
+		}
+		if mux := opts["mux"]; mux != "" {
+			switch mux {
+			case "true":
+				mux = "1"
+			case "false":
+				mux = "0"
+			}
+			values = append(values, "mux="+mux)
+		}
+		return SSPluginV2RayPlugin, strings.Join(values, ";"), nil
+	default:
+		return "", "", fmt.Errorf("unsupported shadowsocks plugin: %s", plugin)
+	}
+}
+
+// NormalizeSSPluginString 将 SIP002 格式的插件名称和选项字符串转换为 sing-box 使用的格式
+func NormalizeSSPluginString(plugin, pluginOpts string) (string, string, error) {
+	// 插件名称中可能带有选项
+	name, opts := ParseSSPluginString(plugin)
+	for key, value := range parseSSPluginOpts(strings.Split(pluginOpts, ";")) {
+		opts[key] = value
+	}
+	return NormalizeSSPlugin(name, opts)
+}
+
+// firstSSPluginOpt 按顺序返回第一个非空的插件选项
+func firstSSPluginOpt(opts map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := opts[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+package models
+
+import "testing"
+
+func TestNormalizeSSPlugin(t *testing.T) {
+	tests := []struct {
+		name       string
+		plugin     string
+		opts       map[string]string
+		wantPlugin string
+		wantOpts   string
+		wantErr    bool
+	}{
+		{name: "no plugin", plugin: ""},
+		{
+			name:       "obfs-local sip002",
+			plugin:     "obfs-local",
+			opts:       map[string]string{"obfs": "tls", "obfs-host": "example.com"},
+			wantPlugin: SSPluginObfsLocal,
+			wantOpts:   "obfs=tls;obfs-host=example.com",
+		},
+		{
+			name:       "simple-obfs alias",
+			plugin:     "simple-obfs",
+			opts:       map[string]string{"obfs": "http"},
+			wantPlugin: SSPluginObfsLocal,
+			wantOpts:   "obfs=http",
+		},
+		{
+			name:       "clash obfs",
+			plugin:     "obfs",
+			opts:       map[string]string{"mode": "tls", "host": "example.com"},
+			wantPlugin: SSPluginObfsLocal,
+			wantOpts:   "obfs=tls;obfs-host=example.com",
+		},
+		{
+			name:       "obfs default mode",
+			plugin:     "Obfs-Local",
+			wantPlugin: SSPluginObfsLocal,
+			wantOpts:   "obfs=http",
+		},
+		{
+			name:    "obfs unsupported mode",
+			plugin:  "obfs-local",
+			opts:    map[string]string{"obfs": "websocket"},
+			wantErr: true,
+		},
+		{
+			name:       "v2ray-plugin sip002",
+			plugin:     "v2ray-plugin",
+			opts:       map[string]string{"tls": "", "host": "example.com", "path": "/ws"},
+			wantPlugin: SSPluginV2RayPlugin,
+			wantOpts:   "mode=websocket;tls;host=example.com;path=/ws",
+		},
+		{
+			name:       "v2ray-plugin clash",
+			plugin:     "v2ray-plugin",
+			opts:       map[string]string{"mode": "websocket", "tls": "true", "mux": "true"},
+			wantPlugin: SSPluginV2RayPlugin,
+			wantOpts:   "mode=websocket;tls;mux=1",
+		},
+		{
+			name:       "v2ray-plugin tls disabled",
+			plugin:     "v2ray-plugin",
+			opts:       map[string]string{"tls": "false", "mux": "false"},
+			wantPlugin: SSPluginV2RayPlugin,
+			wantOpts:   "mode=websocket;mux=0",
+		},
+		{
+			name:    "v2ray-plugin quic mode",
+			plugin:  "v2ray-plugin",
+			opts:    map[string]string{"mode": "quic"},
+			wantErr: true,
+		},
+		{name: "kcptun", plugin: "kcptun", wantErr: true},
+		{name: "shadow-tls", plugin: "shadow-tls", opts: map[string]string{"host": "example.com"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin, opts, err := NormalizeSSPlugin(tt.plugin, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeSSPlugin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if plugin != tt.wantPlugin || opts != tt.wantOpts {
+				t.Errorf("NormalizeSSPlugin() = %q, %q, want %q, %q", plugin, opts, tt.wantPlugin, tt.wantOpts)
+			}
+		})
+	}
+}
+
+func TestNormalizeSSPluginString(t *testing.T) {
+	tests := []struct {
+		plugin     string
+		pluginOpts string
+		wantPlugin string
+		wantOpts   string
+		wantErr    bool
+	}{
+		{plugin: "obfs-local;obfs=http;obfs-host=example.com", wantPlugin: SSPluginObfsLocal, wantOpts: "obfs=http;obfs-host=example.com"},
+		{plugin: "simple-obfs", pluginOpts: "obfs=tls", wantPlugin: SSPluginObfsLocal, wantOpts: "obfs=tls"},
+		{plugin: "v2ray-plugin;tls;host=example.com", pluginOpts: "path=/ws", wantPlugin: SSPluginV2RayPlugin, wantOpts: "mode=websocket;tls;host=example.com;path=/ws"},
+		{plugin: "unknown;foo=bar", wantErr: true},
+	}
+	for _, tt := range tests {
+		plugin, opts, err := NormalizeSSPluginString(tt.plugin, tt.pluginOpts)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizeSSPluginString(%q, %q) error = %v, wantErr %v", tt.plugin, tt.pluginOpts, err, tt.wantErr)
+			continue
+		}
+		if plugin != tt.wantPlugin || opts != tt.wantOpts {
+			t.Errorf("NormalizeSSPluginString(%q, %q) = %q, %q, want %q, %q", tt.plugin, tt.pluginOpts, plugin, opts, tt.wantPlugin, tt.wantOpts)
+		}
+	}
+}
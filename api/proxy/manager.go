@@ -9,9 +9,11 @@ import (
 	"os/exec"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"singdns/api/models"
+	"singdns/api/storage"
 
 	"github.com/sirupsen/logrus"
 )
@@ -24,15 +26,55 @@ type Manager struct {
 	workDir    string
 	startTime  time.Time
 	version    string
+	storage    storage.Storage
+	sup        supervisor
+
+	firewallMu sync.Mutex
+	firewall   *firewallState // 最近一次成功设置的防火墙规则状态
+}
+
+// firewallTable 程序使用的 nftables 表，设置和清除规则时只操作该表
+const firewallTable = "inet sing-box"
+
+// resetFirewallTable 在同一事务中删除本程序的表，表不存在时先创建以免删除失败
+var resetFirewallTable = fmt.Sprintf("table %[1]s\ndelete table %[1]s\n", firewallTable)
+
+// firewallState 防火墙规则依赖的入站模式和设置，未改变时重新加载配置不需要重设规则
+type firewallState struct {
+	mode         string
+	tunInterface string
+	tproxyPort   int
+	redirectPort int
+	dnsPort      int
+	dnsInbound   bool
+	routingMark  int
+	routeTable   int
+	ipv6         bool
 }
 
 // NewManager creates a new proxy manager
-func NewManager(logger *logrus.Logger, configPath string, workDir string) *Manager {
+func NewManager(logger *logrus.Logger, configPath string, workDir string, storage storage.Storage) *Manager {
 	return &Manager{
 		logger:     logger,
 		configPath: configPath,
 		workDir:    workDir,
+		storage:    storage,
+	}
+}
+
+// loadSettings 读取与配置生成器共用的入站设置
+func (m *Manager) loadSettings() *models.Settings {
+	settings, err := m.storage.GetSettings()
+	if err != nil {
+		m.logger.WithError(err).Warn("Failed to get settings, using defaults")
+		settings = &models.Settings{}
+	}
+	if err := settings.ValidateInbound(); err != nil {
+		m.logger.WithError(err).Warn("Invalid inbound settings, using defaults")
+		settings = &models.Settings{EnableIPv6: settings.EnableIPv6}
+		settings.ValidateInbound()
 	}
+	return settings
 }
 
 // getLocalNetwork 获取本机网络信息
@@ -119,42 +161,74 @@ func (m *Manager) getTunInterface() (string, error) {
 }
 
 // setupFirewallRules 设置防火墙规则
-func (m *Manager) setupFirewallRules(mode string) error {
+func (m *Manager) setupFirewallRules(mode string, settings *models.Settings) error {
 	// 获取本地网络信息
 	localIP, localNet, gateway, err := m.getLocalNetwork()
 	if err != nil {
 		return fmt.Errorf("get local network: %v", err)
 	}
 
-	// 首先清除现有规则
-	flushCmd := exec.Command("nft", "flush", "ruleset")
-	var flushStderr bytes.Buffer
-	flushCmd.Stderr = &flushStderr
-	if err = flushCmd.Run(); err != nil {
-		m.logger.WithFields(logrus.Fields{
-			"error":  err,
-			"stderr": flushStderr.String(),
-		}).Warn("Failed to flush nftables rules")
+	// 启用 IPv6 时生成 ip6 规则
+	ipv6 := settings.EnableIPv6
+	var ip6Return, ip6TProxy, ip6Input, ip6Forward, ip6SNAT, localNet6 string
+	if ipv6 {
+		ip6Return = `
+        # 放行 IPv6 保留地址和链路本地地址
+        ip6 daddr { ::/128, ::1/128, fe80::/10, ff00::/8 } return
+`
+		localIPv6, localNets, err := m.getLocalIPv6Network(m.getDefaultInterface())
+		if err != nil {
+			m.logger.WithError(err).Warn("Failed to get local IPv6 addresses")
+		}
+		if len(localIPv6) > 0 {
+			addrs := strings.Join(localIPv6, ", ")
+			localNet6 = strings.Join(localNets, ", ")
+			ip6Return += fmt.Sprintf(`
+        # 放行访问本机 IPv6 地址的流量
+        ip6 daddr { %[1]s } return
+
+        # 放行访问局域网 IPv6 地址的流量
+        ip6 daddr { %[2]s } return
+`, addrs, localNet6)
+			ip6Input = fmt.Sprintf(`
+        ip6 saddr { %s } ip6 daddr { %s } accept`, localNet6, addrs)
+			ip6Forward = fmt.Sprintf(`
+        ip6 saddr { %s } accept`, localNet6)
+			ip6SNAT = fmt.Sprintf(`
+        counter ip6 saddr { %[1]s } ip6 daddr != { %[1]s } masquerade`, localNet6)
+		}
+		ip6TProxy = fmt.Sprintf(`
+        meta l4proto udp counter tproxy ip6 to :%d mark %#x
+`, settings.TProxyPort, settings.RoutingMark)
+	}
+
+	// dns-in 启用时将 DNS 查询重定向到 dns-in
+	var dnsRedirect string
+	if settings.DNSInboundEnabled() {
+		dnsRedirect = fmt.Sprintf(`# DNS 查询重定向到 dns-in
+        tcp dport 53 meta l4proto tcp counter redirect to :%[1]d
+        udp dport 53 meta l4proto udp counter redirect to :%[1]d
+`, settings.DNSPort)
 	}
 
 	var rules string
 	if mode == "redirect" {
 		// Redirect TCP + TProxy UDP 模式的规则
 		rules = fmt.Sprintf(`
-table inet sing-box {
+table %[10]s {
     chain input {
         type filter hook input priority filter; policy accept;
         # 放行本地回环
         iifname "lo" accept
         
         # 放行局域网设备访问本机的流量（目标是本机IP的流量）
-        ip saddr %[1]s ip daddr %[3]s accept
+        ip saddr %[1]s ip daddr %[3]s accept%[11]s
     }
 
     chain forward_filter {
         type filter hook forward priority filter; policy accept;
         # 放行本机转发的流量
-        ip saddr %[1]s accept
+        ip saddr %[1]s accept%[12]s
     }
 
     chain prerouting_mangle {
@@ -170,9 +244,10 @@ table inet sing-box {
         
         # 放行本机访问网关的流量
         ip daddr %[2]s return
-        
+        %[4]s
         # UDP 流量使用 TPROXY（包括 DNS）
-        meta l4proto udp counter tproxy ip to :7893 mark 0x1
+        meta l4proto udp counter tproxy ip to :%[6]d mark %#[8]x
+        %[5]s
     }
 
     chain output_mangle {
@@ -188,33 +263,32 @@ table inet sing-box {
         
         # 放行本机访问网关的流量
         ip daddr %[2]s return
-        
+        %[4]s
         # 标记 UDP 流量
-        meta l4proto udp counter mark 0x1
+        meta l4proto udp counter mark %#[8]x
     }
 
     chain prerouting_dnat {
         type nat hook prerouting priority dstnat; policy accept;
+        %[9]s
         # 放行访问本机的流量
         ip daddr %[3]s return
         
         # 放行本机访问网关的流量
         ip daddr %[2]s return
-        
-        # DNS 查询重定向到 dns-in
-        tcp dport 53 meta l4proto tcp counter redirect to :5353
-        udp dport 53 meta l4proto udp counter redirect to :5353
-        
+        %[4]s
         # TCP 流量重定向
-        meta l4proto tcp counter redirect to :7892
+        meta l4proto tcp counter redirect to :%[7]d
     }
 
     chain postrouting_snat {
         type nat hook postrouting priority srcnat; policy accept;
         # 对其他设备的流量进行 MASQUERADE
-        counter ip saddr %[1]s ip daddr != %[2]s masquerade
+        counter ip saddr %[1]s ip daddr != %[2]s masquerade%[13]s
     }
-}`, localNet, gateway, localIP)
+}`, localNet, gateway, localIP, ip6Return, ip6TProxy,
+			settings.TProxyPort, settings.RedirectPort, settings.RoutingMark, dnsRedirect, firewallTable,
+			ip6Input, ip6Forward, ip6SNAT)
 
 		// 开启 IP 转发和 TProxy 支持
 		if err := m.execCommand("echo 1 > /proc/sys/net/ipv4/ip_forward"); err != nil {
@@ -225,33 +299,67 @@ table inet sing-box {
 			return fmt.Errorf("enable ip nonlocal bind: %w", err)
 		}
 
-		// 设置策略路由
-		if err := m.execCommand("ip rule del fwmark 0x1 table 100 2>/dev/null || true"); err != nil {
-			m.logger.WithError(err).Warn("Failed to delete existing ip rule")
-		}
-
-		if err := m.execCommand("ip route del local 0.0.0.0/0 dev lo table 100 2>/dev/null || true"); err != nil {
-			m.logger.WithError(err).Warn("Failed to delete existing ip route")
-		}
+		// 设置策略路由，关闭 IPv6 后也删除之前的 IPv6 策略路由
+		m.clearPolicyRoutes(settings.RoutingMark, settings.RouteTable)
 
-		if err := m.execCommand("ip rule add fwmark 0x1 lookup 100"); err != nil {
+		if err := m.execCommand(fmt.Sprintf("ip rule add fwmark %#x lookup %d", settings.RoutingMark, settings.RouteTable)); err != nil {
 			return fmt.Errorf("add ip rule: %w", err)
 		}
 
-		if err := m.execCommand("ip route add local 0.0.0.0/0 dev lo table 100"); err != nil {
+		if err := m.execCommand(fmt.Sprintf("ip route add local 0.0.0.0/0 dev lo table %d", settings.RouteTable)); err != nil {
 			return fmt.Errorf("add ip route: %w", err)
 		}
+
+		if ipv6 {
+			if err := m.setupIPv6PolicyRoute(settings.RoutingMark, settings.RouteTable); err != nil {
+				return err
+			}
+		}
 	} else if mode == "tun" {
+		if ipv6 {
+			if err := m.execCommand("echo 1 > /proc/sys/net/ipv6/conf/all/forwarding"); err != nil {
+				m.logger.WithError(err).Warn("Failed to enable IPv6 forwarding")
+			}
+		}
+
+		// TUN 接口名称与生成的配置保持一致
+		tunInterface, err := m.getTunInterface()
+		if err != nil {
+			m.logger.WithError(err).Warn("Failed to get tun interface, using tun0")
+			tunInterface = "tun0"
+		}
+
+		// 局域网设备发往本机 53 端口的查询交给 dns-in
+		var dnsPrerouting string
+		if settings.DNSInboundEnabled() && settings.DNSPort != 53 {
+			dnsPrerouting = fmt.Sprintf(`
+    chain prerouting {
+        type nat hook prerouting priority dstnat; policy accept;
+        ip daddr %[1]s tcp dport 53 counter redirect to :%[2]d
+        ip daddr %[1]s udp dport 53 counter redirect to :%[2]d
+    }
+`, localIP, settings.DNSPort)
+		}
+
+		var ip6TunSNAT string
+		if ipv6 && localNet6 != "" {
+			ip6TunSNAT = fmt.Sprintf(`
+        counter ip6 saddr { %[1]s } ip6 daddr != { %[1]s } oifname "%[2]s" masquerade`, localNet6, tunInterface)
+		}
+
 		// TUN 模式的规则
 		rules = fmt.Sprintf(`
-table ip nat {
+table %[5]s {%[4]s
     chain postrouting {
         type nat hook postrouting priority 100; policy accept;
-        counter ip saddr %[1]s ip daddr != %[2]s oifname "tun0" masquerade
+        counter ip saddr %[1]s ip daddr != %[2]s oifname "%[3]s" masquerade%[6]s
     }
-}`, localNet, gateway)
+}`, localNet, gateway, tunInterface, dnsPrerouting, firewallTable, ip6TunSNAT)
 	}
 
+	// 先删除本程序的表再重新创建，不影响系统或其他程序的规则
+	rules = resetFirewallTable + rules
+
 	// 写入临时文件
 	var nftfile *os.File
 	nftfile, err = os.CreateTemp("", "nftables-rules-*.nft")
@@ -284,6 +392,47 @@ table ip nat {
 	return nil
 }
 
+// setupIPv6PolicyRoute 开启 IPv6 转发并设置 TProxy 使用的 IPv6 策略路由
+func (m *Manager) setupIPv6PolicyRoute(mark, table int) error {
+	if err := m.execCommand("echo 1 > /proc/sys/net/ipv6/conf/all/forwarding"); err != nil {
+		return fmt.Errorf("enable ipv6 forward: %w", err)
+	}
+
+	if err := m.execCommand(fmt.Sprintf("ip -6 rule add fwmark %#x lookup %d", mark, table)); err != nil {
+		return fmt.Errorf("add ip -6 rule: %w", err)
+	}
+
+	if err := m.execCommand(fmt.Sprintf("ip -6 route add local ::/0 dev lo table %d", table)); err != nil {
+		return fmt.Errorf("add ip -6 route: %w", err)
+	}
+	return nil
+}
+
+// getLocalIPv6Network 获取网卡的全局 IPv6 地址和所在的局域网前缀
+func (m *Manager) getLocalIPv6Network(iface string) ([]string, []string, error) {
+	cmd := exec.Command("ip", "-6", "addr", "show", iface, "scope", "global")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, nil, fmt.Errorf("get interface ipv6 addr: %v", err)
+	}
+
+	var addrs, nets []string
+	seen := make(map[string]bool)
+	re := regexp.MustCompile(`inet6\s+([0-9a-fA-F:]+/\d+)`)
+	for _, match := range re.FindAllStringSubmatch(string(output), -1) {
+		ip, ipNet, err := net.ParseCIDR(match[1])
+		if err != nil {
+			continue
+		}
+		addrs = append(addrs, ip.String())
+		if !seen[ipNet.String()] {
+			seen[ipNet.String()] = true
+			nets = append(nets, ipNet.String())
+		}
+	}
+	return addrs, nets, nil
+}
+
 // execCommand 执行命令
 func (m *Manager) execCommand(command string) error {
 	cmd := exec.Command("sh", "-c", command)
@@ -316,18 +465,21 @@ func (m *Manager) Start() error {
 		"configPath": m.configPath,
 	}).Info("Starting sing-box service")
 
-	if m.IsRunning() {
+	m.sup.mu.Lock()
+	if m.cmd != nil {
+		m.sup.mu.Unlock()
 		m.logger.Warn("Service is already running")
 		return fmt.Errorf("service is already running")
 	}
+	// 手动启动时重新计算连续崩溃次数
+	m.resetSupervisor()
+	m.sup.mu.Unlock()
 
-	// Kill any existing sing-box processes
-	if err := exec.Command("pkill", "-f", "sing-box").Run(); err != nil {
-		m.logger.WithError(err).Debug("No existing sing-box processes found")
-	}
+	// 停止上次运行遗留的 sing-box 进程
+	m.stopStaleProcess()
 
 	// Check if sing-box binary exists
-	binPath := fmt.Sprintf("%s/bin/sing-box", m.workDir)
+	binPath := m.binPath()
 	if _, err := os.Stat(binPath); os.IsNotExist(err) {
 		m.logger.WithField("path", binPath).Error("sing-box binary not found")
 		return fmt.Errorf("sing-box binary not found at %s", binPath)
@@ -377,32 +529,32 @@ func (m *Manager) Start() error {
 		}
 	}
 
-	// Create command
-	m.cmd = exec.Command(binPath, "run", "-c", m.configPath)
-	m.cmd.Dir = m.workDir
-
-	// Capture command output
-	var stdout, stderr bytes.Buffer
-	m.cmd.Stdout = &stdout
-	m.cmd.Stderr = &stderr
-
 	// Start the process
-	m.startTime = time.Now()
-	if err := m.cmd.Start(); err != nil {
+	m.sup.mu.Lock()
+	if m.cmd != nil {
+		m.sup.mu.Unlock()
+		return fmt.Errorf("service is already running")
+	}
+	output, err := m.launch(binPath)
+	exited := m.sup.exited
+	m.sup.mu.Unlock()
+	if err != nil {
 		m.logger.WithError(err).Error("Failed to start service")
 		return fmt.Errorf("failed to start service: %v", err)
 	}
 
 	// Wait a bit to ensure process started successfully
-	time.Sleep(time.Second)
-	if !m.IsRunning() {
-		output := stdout.String()
-		errOutput := stderr.String()
-		m.logger.WithFields(logrus.Fields{
-			"stdout": output,
-			"stderr": errOutput,
-		}).Error("Service failed to start")
-		return fmt.Errorf("service failed to start. Stdout: %s, Stderr: %s", output, errOutput)
+	select {
+	case <-exited:
+		// 启动阶段的退出直接返回错误，不进行自动重启
+		m.sup.mu.Lock()
+		m.cancelRestart()
+		m.sup.failures = 0
+		m.sup.mu.Unlock()
+		errOutput := output.String()
+		m.logger.WithField("output", errOutput).Error("Service failed to start")
+		return fmt.Errorf("service failed to start. Output: %s", errOutput)
+	case <-time.After(time.Second):
 	}
 
 	m.logger.Info("sing-box service started successfully")
@@ -410,42 +562,97 @@ func (m *Manager) Start() error {
 	// 等待一会儿让 sing-box 完全启动并创建接口
 	time.Sleep(time.Second * 2)
 
+	// 设置防火墙规则
+	if err := m.applyFirewallRules(true); err != nil {
+		m.logger.WithError(err).Error("Failed to setup firewall rules")
+		// 这里我们不返回错误,因为服务已经启动成功
+	}
+
+	return nil
+}
+
+// applyFirewallRules 根据配置文件中的入站确定模式并设置防火墙规则
+// force 为 false 时，入站模式和端口等设置未改变则保留现有规则
+func (m *Manager) applyFirewallRules(force bool) error {
+	m.firewallMu.Lock()
+	defer m.firewallMu.Unlock()
+
+	state, settings, err := m.currentFirewallState()
+	if err != nil {
+		return err
+	}
+	if !force && m.firewall != nil && *m.firewall == state {
+		m.logger.Debug("Inbound settings unchanged, keep firewall rules")
+		return nil
+	}
+
+	m.firewall = nil
+	if err := m.setupFirewallRules(state.mode, settings); err != nil {
+		return err
+	}
+	m.firewall = &state
+	return nil
+}
+
+// currentFirewallState 读取配置文件中的入站和入站设置，确定防火墙规则状态
+func (m *Manager) currentFirewallState() (firewallState, *models.Settings, error) {
 	// 读取配置文件以确定模式
 	configData, err := os.ReadFile(m.configPath)
 	if err != nil {
-		return fmt.Errorf("read config file: %v", err)
+		return firewallState{}, nil, fmt.Errorf("read config file: %v", err)
 	}
 
 	var config struct {
 		Inbounds []struct {
-			Type string `json:"type"`
+			Type          string `json:"type"`
+			InterfaceName string `json:"interface_name"`
 		} `json:"inbounds"`
 	}
 	if err := json.Unmarshal(configData, &config); err != nil {
-		return fmt.Errorf("parse config: %v", err)
+		return firewallState{}, nil, fmt.Errorf("parse config: %v", err)
 	}
 
 	// 确定模式
-	mode := "tun"
+	settings := m.loadSettings()
+	state := firewallState{
+		mode:         "tun",
+		tproxyPort:   settings.TProxyPort,
+		redirectPort: settings.RedirectPort,
+		dnsPort:      settings.DNSPort,
+		dnsInbound:   settings.DNSInboundEnabled(),
+		routingMark:  settings.RoutingMark,
+		routeTable:   settings.RouteTable,
+		ipv6:         settings.EnableIPv6,
+	}
 	for _, inbound := range config.Inbounds {
-		if inbound.Type == "redirect" {
-			mode = "redirect"
-			break
+		switch inbound.Type {
+		case "redirect":
+			state.mode = "redirect"
+		case "tun":
+			state.tunInterface = inbound.InterfaceName
 		}
 	}
-
-	// 设置防火墙规则
-	if err := m.setupFirewallRules(mode); err != nil {
-		m.logger.WithError(err).Error("Failed to setup firewall rules")
-		// 这里我们不返回错误,因为服务已经启动成功
-	}
-
-	return nil
+	return state, settings, nil
 }
 
-// clearFirewallRules 清除防火墙规则
+// clearFirewallRules 删除本程序的防火墙规则和 TProxy 策略路由
 func (m *Manager) clearFirewallRules() error {
-	cmd := exec.Command("nft", "flush", "ruleset")
+	m.firewallMu.Lock()
+	defer m.firewallMu.Unlock()
+
+	// 优先使用设置规则时的标记和路由表，之后修改过设置时也能删除旧的策略路由
+	var mark, table int
+	if m.firewall != nil {
+		mark, table = m.firewall.routingMark, m.firewall.routeTable
+	} else {
+		settings := m.loadSettings()
+		mark, table = settings.RoutingMark, settings.RouteTable
+	}
+	m.firewall = nil
+	m.clearPolicyRoutes(mark, table)
+
+	cmd := exec.Command("nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(resetFirewallTable)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
@@ -460,9 +667,35 @@ func (m *Manager) clearFirewallRules() error {
 	return nil
 }
 
+// clearPolicyRoutes 删除 TProxy 使用的 IPv4 和 IPv6 策略路由，不存在时忽略
+func (m *Manager) clearPolicyRoutes(mark, table int) {
+	if err := m.execCommand(fmt.Sprintf("ip rule del fwmark %#x table %d 2>/dev/null || true", mark, table)); err != nil {
+		m.logger.WithError(err).Warn("Failed to delete ip rule")
+	}
+	if err := m.execCommand(fmt.Sprintf("ip route del local 0.0.0.0/0 dev lo table %d 2>/dev/null || true", table)); err != nil {
+		m.logger.WithError(err).Warn("Failed to delete ip route")
+	}
+	if err := m.execCommand(fmt.Sprintf("ip -6 rule del fwmark %#x table %d 2>/dev/null || true", mark, table)); err != nil {
+		m.logger.WithError(err).Warn("Failed to delete ip -6 rule")
+	}
+	if err := m.execCommand(fmt.Sprintf("ip -6 route del local ::/0 dev lo table %d 2>/dev/null || true", table)); err != nil {
+		m.logger.WithError(err).Warn("Failed to delete ip -6 route")
+	}
+}
+
 // Stop stops the sing-box service
 func (m *Manager) Stop() error {
-	if !m.IsRunning() {
+	m.sup.mu.Lock()
+	cmd := m.cmd
+	exited := m.sup.exited
+	pending := m.cancelRestart()
+	gaveUp := m.sup.gaveUp
+	m.cmd = nil
+	m.sup.gaveUp = false
+	m.sup.mu.Unlock()
+
+	// 崩溃后等待重启或已放弃重启时仍需清理防火墙规则
+	if cmd == nil && !pending && !gaveUp {
 		return fmt.Errorf("service is not running")
 	}
 
@@ -471,43 +704,26 @@ func (m *Manager) Stop() error {
 		m.logger.WithError(err).Warn("Failed to clear firewall rules, continuing with service stop")
 	}
 
-	// Kill the process
-	if err := m.cmd.Process.Kill(); err != nil {
-		return fmt.Errorf("kill process: %v", err)
+	if cmd != nil {
+		if err := m.terminate(cmd.Process, exited); err != nil {
+			return err
+		}
 	}
 
-	m.cmd = nil
 	m.logger.Info("Service stopped")
 	return nil
 }
 
 // IsRunning returns whether the service is running
 func (m *Manager) IsRunning() bool {
-	if m.cmd == nil || m.cmd.Process == nil {
-		return false
-	}
-
-	// 使用 pgrep 检查进程
-	cmd := exec.Command("pgrep", "-f", "sing-box")
-	output, err := cmd.Output()
-	if err != nil {
-		m.cmd = nil
-		return false
-	}
-
-	// 检查进程是否真的在运行
-	if len(output) == 0 {
-		m.cmd = nil
-		return false
-	}
-
-	// 检查配置文件是否存在
-	if _, err := os.Stat(m.configPath); os.IsNotExist(err) {
-		m.cmd = nil
-		return false
-	}
+	m.sup.mu.Lock()
+	defer m.sup.mu.Unlock()
+	return m.cmd != nil
+}
 
-	return true
+// binPath 返回 sing-box 可执行文件路径
+func (m *Manager) binPath() string {
+	return fmt.Sprintf("%s/bin/sing-box", m.workDir)
 }
 
 // GetVersion returns the version of sing-box
@@ -556,6 +772,16 @@ func (m *Manager) StopService(service string) error {
 	}
 }
 
+// ReloadService reloads the config of the specified service
+func (m *Manager) ReloadService(service string) error {
+	switch service {
+	case "sing-box":
+		return m.Reload()
+	default:
+		return fmt.Errorf("unknown service: %s", service)
+	}
+}
+
 // RestartService restarts the specified service
 func (m *Manager) RestartService(service string) error {
 	switch service {
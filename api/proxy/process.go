@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// stopTimeout 发送 SIGTERM 后等待进程退出的时间，超时后发送 SIGKILL
+const stopTimeout = 10 * time.Second
+
+// pidFilePath 返回 PID 文件路径，与配置文件放在同一目录
+func (m *Manager) pidFilePath() string {
+	return filepath.Join(filepath.Dir(m.configPath), "sing-box.pid")
+}
+
+// writePIDFile 记录当前启动的 sing-box 进程
+func (m *Manager) writePIDFile(pid int) error {
+	return os.WriteFile(m.pidFilePath(), []byte(strconv.Itoa(pid)+"\n"), 0644)
+}
+
+// removePIDFile 删除 PID 文件
+func (m *Manager) removePIDFile() {
+	if err := os.Remove(m.pidFilePath()); err != nil && !os.IsNotExist(err) {
+		m.logger.WithError(err).Warn("Failed to remove pid file")
+	}
+}
+
+// terminate 先发送 SIGTERM，超时后发送 SIGKILL，exited 在进程退出时关闭
+func (m *Manager) terminate(process *os.Process, exited <-chan struct{}) error {
+	if err := process.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("send SIGTERM: %v", err)
+	}
+
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(stopTimeout):
+	}
+
+	m.logger.Warnf("sing-box did not exit within %s, killing it", stopTimeout)
+	if err := process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("kill process: %v", err)
+	}
+	<-exited
+	return nil
+}
+
+// stopStaleProcess 停止 PID 文件中记录的、上次运行遗留的 sing-box 进程
+func (m *Manager) stopStaleProcess() {
+	data, err := os.ReadFile(m.pidFilePath())
+	if err != nil {
+		return
+	}
+	defer m.removePIDFile()
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return
+	}
+
+	// PID 可能已被其他进程复用，只处理使用本配置文件运行的 sing-box
+	cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return
+	}
+	args := strings.Split(string(bytes.TrimRight(cmdline, "\x00")), "\x00")
+	if len(args) == 0 || filepath.Base(args[0]) != "sing-box" || !containsArg(args, m.configPath) {
+		return
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return
+	}
+	m.logger.WithField("pid", pid).Warn("Stopping stale sing-box process")
+
+	// 非子进程无法 Wait，通过信号 0 轮询是否退出
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		for process.Signal(syscall.Signal(0)) == nil {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+	if err := m.terminate(process, exited); err != nil {
+		m.logger.WithError(err).Warn("Failed to stop stale sing-box process")
+	}
+}
+
+// containsArg 判断命令行参数中是否包含 arg
+func containsArg(args []string, arg string) bool {
+	for _, a := range args {
+		if a == arg {
+			return true
+		}
+	}
+	return false
+}
+
+// Reload 发送 SIGHUP 让 sing-box 在原进程内重新加载配置文件
+func (m *Manager) Reload() error {
+	m.sup.mu.Lock()
+	cmd := m.cmd
+	m.sup.mu.Unlock()
+	if cmd == nil {
+		return fmt.Errorf("service is not running")
+	}
+
+	if err := cmd.Process.Signal(syscall.SIGHUP); err != nil {
+		return fmt.Errorf("send SIGHUP: %v", err)
+	}
+	m.logger.Info("sing-box config reloaded")
+
+	// 只在入站模式或端口改变时重新设置防火墙规则
+	if err := m.applyFirewallRules(false); err != nil {
+		m.logger.WithError(err).Error("Failed to setup firewall rules")
+	}
+	return nil
+}
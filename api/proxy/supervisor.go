@@ -0,0 +1,265 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 崩溃重启策略
+const (
+	restartBaseDelay   = time.Second     // 第一次重启前的等待时间，之后每次翻倍
+	restartMaxDelay    = time.Minute     // 重启等待时间上限
+	maxRestartAttempts = 5               // 连续崩溃超过该次数后不再重启
+	stableRunDuration  = 2 * time.Minute // 运行超过该时间后崩溃不计入连续崩溃次数
+	outputTailSize     = 4096            // 保留的 sing-box 输出字节数
+)
+
+// 进程状态
+const (
+	ProcessStateStopped    = "stopped"
+	ProcessStateRunning    = "running"
+	ProcessStateRestarting = "restarting" // 崩溃后等待重启
+	ProcessStateCrashed    = "crashed"    // 连续崩溃，已停止重启
+)
+
+// ProcessStatus sing-box 进程的监控状态
+type ProcessStatus struct {
+	State           string     `json:"state"`
+	PID             int        `json:"pid"`
+	RestartCount    int        `json:"restart_count"` // 自上次手动启动以来的自动重启次数
+	LastExitCode    *int       `json:"last_exit_code"`
+	LastCrashReason string     `json:"last_crash_reason"`
+	LastCrashAt     *time.Time `json:"last_crash_at"`
+}
+
+// supervisor 记录进程崩溃和重启状态
+type supervisor struct {
+	mu           sync.Mutex
+	exited       chan struct{} // 当前进程退出时关闭
+	restartTimer *time.Timer
+	restartGen   int // 用于识别已取消的重启
+	failures     int // 连续崩溃次数
+	restartCount int
+	gaveUp       bool
+	lastExitCode *int
+	lastReason   string
+	lastCrashAt  *time.Time
+}
+
+// tailBuffer 只保留最后 size 字节的输出，避免长时间运行时占用过多内存
+type tailBuffer struct {
+	mu   sync.Mutex
+	size int
+	buf  []byte
+}
+
+// Write implements io.Writer
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.size {
+		t.buf = append([]byte(nil), t.buf[len(t.buf)-t.size:]...)
+	}
+	return len(p), nil
+}
+
+// String 返回保留的输出
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
+
+// restartDelay 计算第 failures 次连续崩溃后的重启等待时间
+func restartDelay(failures int) time.Duration {
+	delay := restartBaseDelay
+	for i := 1; i < failures && delay < restartMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > restartMaxDelay {
+		delay = restartMaxDelay
+	}
+	return delay
+}
+
+// exitCode 从 cmd.Wait 的返回值中获取退出码，被信号终止时返回 -1
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// crashReason 根据退出错误和最后的输出生成崩溃原因
+func crashReason(err error, output string) string {
+	reason := "exited with status 0"
+	if err != nil {
+		reason = err.Error()
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		reason = fmt.Sprintf("%s: %s", reason, last)
+	}
+	return reason
+}
+
+// launch 启动 sing-box 进程并在后台等待其退出，调用方需持有 m.sup.mu
+func (m *Manager) launch(binPath string) (*tailBuffer, error) {
+	output := &tailBuffer{size: outputTailSize}
+	cmd := exec.Command(binPath, "run", "-c", m.configPath)
+	cmd.Dir = m.workDir
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	m.cmd = cmd
+	m.startTime = time.Now()
+	if err := m.writePIDFile(cmd.Process.Pid); err != nil {
+		m.logger.WithError(err).Warn("Failed to write pid file")
+	}
+	m.sup.exited = make(chan struct{})
+	go m.wait(cmd, m.sup.exited, output)
+	return output, nil
+}
+
+// wait 等待进程退出，非主动停止的退出视为崩溃并按退避策略重启
+func (m *Manager) wait(cmd *exec.Cmd, exited chan struct{}, output *tailBuffer) {
+	err := cmd.Wait()
+	m.removePIDFile()
+
+	m.sup.mu.Lock()
+	defer m.sup.mu.Unlock()
+	// 状态更新完成后再通知等待方
+	defer close(exited)
+
+	// 进程已被 Stop 停止或替换
+	if m.cmd != cmd {
+		return
+	}
+	m.cmd = nil
+
+	now := time.Now()
+	code := exitCode(err)
+	m.sup.lastExitCode = &code
+	m.sup.lastReason = crashReason(err, output.String())
+	m.sup.lastCrashAt = &now
+
+	if now.Sub(m.startTime) >= stableRunDuration {
+		m.sup.failures = 0
+	}
+	m.sup.failures++
+	if m.sup.failures > maxRestartAttempts {
+		m.logger.WithField("reason", m.sup.lastReason).Errorf("sing-box crashed %d times in a row, giving up", m.sup.failures)
+		m.giveUp()
+		return
+	}
+
+	delay := m.scheduleRestart()
+	m.logger.WithFields(logrus.Fields{
+		"exit_code": code,
+		"reason":    m.sup.lastReason,
+		"delay":     delay.String(),
+	}).Warn("sing-box crashed, restarting")
+}
+
+// scheduleRestart 按连续崩溃次数延迟重启，调用方需持有 m.sup.mu
+func (m *Manager) scheduleRestart() time.Duration {
+	delay := restartDelay(m.sup.failures)
+	m.sup.restartGen++
+	gen := m.sup.restartGen
+	m.sup.restartTimer = time.AfterFunc(delay, func() { m.restartAfterCrash(gen) })
+	return delay
+}
+
+// restartAfterCrash 崩溃等待结束后重新启动进程
+func (m *Manager) restartAfterCrash(gen int) {
+	m.sup.mu.Lock()
+	defer m.sup.mu.Unlock()
+
+	// 等待期间已被 Stop 或 Start 取消
+	if m.sup.restartTimer == nil || m.sup.restartGen != gen || m.cmd != nil {
+		return
+	}
+	m.sup.restartTimer = nil
+
+	if _, err := m.launch(m.binPath()); err != nil {
+		m.sup.lastReason = fmt.Sprintf("restart failed: %v", err)
+		m.sup.failures++
+		if m.sup.failures > maxRestartAttempts {
+			m.logger.WithError(err).Error("Failed to restart sing-box, giving up")
+			m.giveUp()
+			return
+		}
+		delay := m.scheduleRestart()
+		m.logger.WithError(err).Warnf("Failed to restart sing-box, retrying in %s", delay)
+		return
+	}
+	m.sup.restartCount++
+	m.logger.Infof("sing-box restarted (restart #%d)", m.sup.restartCount)
+}
+
+// giveUp 放弃自动重启，并像 Stop 一样清除防火墙规则和策略路由，
+// 避免局域网流量继续被转发到没有进程监听的端口，调用方需持有 m.sup.mu
+func (m *Manager) giveUp() {
+	m.sup.gaveUp = true
+	if err := m.clearFirewallRules(); err != nil {
+		m.logger.WithError(err).Error("Failed to clear firewall rules after giving up restarting sing-box")
+		return
+	}
+	m.logger.Warn("sing-box is not running, firewall rules and policy routes cleared")
+}
+
+// cancelRestart 取消等待中的自动重启，调用方需持有 m.sup.mu
+func (m *Manager) cancelRestart() bool {
+	if m.sup.restartTimer == nil {
+		return false
+	}
+	m.sup.restartTimer.Stop()
+	m.sup.restartTimer = nil
+	return true
+}
+
+// resetSupervisor 手动启动时清除连续崩溃计数，调用方需持有 m.sup.mu
+func (m *Manager) resetSupervisor() {
+	m.cancelRestart()
+	m.sup.failures = 0
+	m.sup.restartCount = 0
+	m.sup.gaveUp = false
+}
+
+// GetStatus returns the supervised state of the sing-box process
+func (m *Manager) GetStatus() ProcessStatus {
+	m.sup.mu.Lock()
+	defer m.sup.mu.Unlock()
+
+	status := ProcessStatus{
+		State:           ProcessStateStopped,
+		RestartCount:    m.sup.restartCount,
+		LastExitCode:    m.sup.lastExitCode,
+		LastCrashReason: m.sup.lastReason,
+		LastCrashAt:     m.sup.lastCrashAt,
+	}
+	switch {
+	case m.cmd != nil:
+		status.State = ProcessStateRunning
+		status.PID = m.cmd.Process.Pid
+	case m.sup.restartTimer != nil:
+		status.State = ProcessStateRestarting
+	case m.sup.gaveUp:
+		status.State = ProcessStateCrashed
+	}
+	return status
+}
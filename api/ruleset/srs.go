@@ -0,0 +1,280 @@
+package ruleset
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+
+	"singdns/api/storage"
+)
+
+const (
+	// AdBlockRuleSetTag 广告规则集标签
+	AdBlockRuleSetTag = "geosite-category-ads"
+	// AdBlockRuleSetPath 广告规则集文件路径
+	AdBlockRuleSetPath = "configs/sing-box/rules/geosite-category-ads.srs"
+)
+
+// srs 文件头
+const (
+	srsMagic = "SRS"
+	// srsMaxVersion 支持的最高规则集版本
+	srsMaxVersion = 3
+)
+
+// srs 规则项类型
+const (
+	srsRuleTypeDefault = 0
+	srsRuleTypeLogical = 1
+
+	srsItemQueryType          = 0
+	srsItemNetwork            = 1
+	srsItemDomain             = 2
+	srsItemDomainKeyword      = 3
+	srsItemDomainRegex        = 4
+	srsItemSourceIPCIDR       = 5
+	srsItemIPCIDR             = 6
+	srsItemSourcePort         = 7
+	srsItemSourcePortRange    = 8
+	srsItemPort               = 9
+	srsItemPortRange          = 10
+	srsItemProcessName        = 11
+	srsItemProcessPath        = 12
+	srsItemPackageName        = 13
+	srsItemWIFISSID           = 14
+	srsItemWIFIBSSID          = 15
+	srsItemAdGuardDomain      = 16
+	srsItemProcessPathRegex   = 17
+	srsItemNetworkType        = 18
+	srsItemNetworkIsExpensive = 19
+	srsItemNetworkConstrained = 20
+	srsItemFinal              = 0xFF
+)
+
+// CountSRSFile 统计 sing-box 二进制规则集文件中的规则条目数量
+func CountSRSFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return CountSRSRules(data)
+}
+
+// CountSRSRules 统计 sing-box 二进制规则集 (.srs) 中的规则条目数量
+// 域名、关键字、正则和 IP 段等每个值计为一条
+func CountSRSRules(data []byte) (int, error) {
+	if len(data) < 4 || string(data[:3]) != srsMagic {
+		return 0, fmt.Errorf("invalid srs header")
+	}
+	if version := data[3]; version == 0 || version > srsMaxVersion {
+		return 0, fmt.Errorf("unsupported srs version: %d", version)
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(data[4:]))
+	if err != nil {
+		return 0, fmt.Errorf("failed to open srs body: %v", err)
+	}
+	defer zr.Close()
+
+	r := bufio.NewReader(zr)
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read rule count: %v", err)
+	}
+
+	total := 0
+	for i := uint64(0); i < length; i++ {
+		count, err := countSRSRule(r)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// countSRSRule 统计单条规则中的条目数量
+func countSRSRule(r *bufio.Reader) (int, error) {
+	ruleType, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	switch ruleType {
+	case srsRuleTypeDefault:
+		return countSRSDefaultRule(r)
+	case srsRuleTypeLogical:
+		// mode
+		if _, err := r.ReadByte(); err != nil {
+			return 0, err
+		}
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			return 0, err
+		}
+		total := 0
+		for i := uint64(0); i < length; i++ {
+			count, err := countSRSRule(r)
+			if err != nil {
+				return 0, err
+			}
+			total += count
+		}
+		// invert
+		if _, err := r.ReadByte(); err != nil {
+			return 0, err
+		}
+		return total, nil
+	default:
+		return 0, fmt.Errorf("unknown srs rule type: %d", ruleType)
+	}
+}
+
+// countSRSDefaultRule 统计普通规则中的条目数量
+func countSRSDefaultRule(r *bufio.Reader) (int, error) {
+	total := 0
+	for {
+		itemType, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		var count int
+		switch itemType {
+		case srsItemFinal:
+			// invert
+			if _, err := r.ReadByte(); err != nil {
+				return 0, err
+			}
+			return total, nil
+		case srsItemDomain:
+			count, err = countSRSDomainMatcher(r)
+		case srsItemDomainKeyword, srsItemDomainRegex, srsItemNetwork,
+			srsItemSourcePortRange, srsItemPortRange, srsItemProcessName,
+			srsItemProcessPath, srsItemPackageName, srsItemWIFISSID,
+			srsItemWIFIBSSID, srsItemAdGuardDomain, srsItemProcessPathRegex:
+			count, err = countSRSStrings(r)
+		case srsItemSourceIPCIDR, srsItemIPCIDR:
+			count, err = countSRSIPSet(r)
+		case srsItemQueryType, srsItemSourcePort, srsItemPort:
+			count, err = skipSRSSlice(r, 2)
+		case srsItemNetworkType:
+			count, err = skipSRSSlice(r, 1)
+		case srsItemNetworkIsExpensive, srsItemNetworkConstrained:
+		default:
+			return 0, fmt.Errorf("unknown srs rule item type: %d", itemType)
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+}
+
+// countSRSDomainMatcher 统计 succinct 域名集合中的域名数量
+func countSRSDomainMatcher(r *bufio.Reader) (int, error) {
+	// version
+	if _, err := r.ReadByte(); err != nil {
+		return 0, err
+	}
+
+	// leaves 中每个置位代表一个域名
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	buf := make([]byte, 8)
+	for i := uint64(0); i < length; i++ {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return 0, err
+		}
+		count += bits.OnesCount64(binary.BigEndian.Uint64(buf))
+	}
+
+	// labelBitmap
+	if _, err := skipSRSSlice(r, 8); err != nil {
+		return 0, err
+	}
+	// labels
+	if _, err := skipSRSSlice(r, 1); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// countSRSStrings 统计字符串列表的长度
+func countSRSStrings(r *bufio.Reader) (int, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, err
+	}
+	for i := uint64(0); i < length; i++ {
+		if _, err := skipSRSSlice(r, 1); err != nil {
+			return 0, err
+		}
+	}
+	return int(length), nil
+}
+
+// countSRSIPSet 统计 IP 集合中的地址段数量
+func countSRSIPSet(r *bufio.Reader) (int, error) {
+	// version
+	if _, err := r.ReadByte(); err != nil {
+		return 0, err
+	}
+	var length uint64
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return 0, err
+	}
+	for i := uint64(0); i < length; i++ {
+		// from
+		if _, err := skipSRSSlice(r, 1); err != nil {
+			return 0, err
+		}
+		// to
+		if _, err := skipSRSSlice(r, 1); err != nil {
+			return 0, err
+		}
+	}
+	return int(length), nil
+}
+
+// skipSRSSlice 跳过以 uvarint 长度开头的定长元素列表
+func skipSRSSlice(r *bufio.Reader, size int) (int, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := r.Discard(int(length) * size); err != nil {
+		return 0, err
+	}
+	return int(length), nil
+}
+
+// RefreshAdBlockStats 根据广告规则集文件更新 DNS 设置中的广告规则数量和更新时间
+func RefreshAdBlockStats(store storage.Storage) error {
+	info, err := os.Stat(AdBlockRuleSetPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat ad block rule set: %v", err)
+	}
+
+	count, err := CountSRSFile(AdBlockRuleSetPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse ad block rule set: %v", err)
+	}
+
+	settings, err := store.GetDNSSettings()
+	if err != nil {
+		return fmt.Errorf("failed to get dns settings: %v", err)
+	}
+
+	settings.AdBlockRuleCount = count
+	settings.LastUpdate = info.ModTime().Unix()
+	return store.SaveDNSSettings(settings)
+}
@@ -0,0 +1,134 @@
+package ruleset
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"testing"
+)
+
+// srsWriter 按 sing-box 二进制规则集格式写入测试数据
+type srsWriter struct {
+	bytes.Buffer
+}
+
+func (w *srsWriter) uvarint(v uint64) {
+	w.Write(binary.AppendUvarint(nil, v))
+}
+
+func (w *srsWriter) strings(values ...string) {
+	w.uvarint(uint64(len(values)))
+	for _, v := range values {
+		w.uvarint(uint64(len(v)))
+		w.WriteString(v)
+	}
+}
+
+// domains 写入 leaves 中置位 count 个的域名集合
+func (w *srsWriter) domains(count int) {
+	w.WriteByte(1)
+	w.uvarint(1)
+	binary.Write(w, binary.BigEndian, uint64(1)<<count-1)
+	w.uvarint(1)
+	binary.Write(w, binary.BigEndian, uint64(0))
+	w.uvarint(3)
+	w.WriteString("com")
+}
+
+func (w *srsWriter) ipCIDRs(count int) {
+	w.WriteByte(1)
+	binary.Write(w, binary.BigEndian, uint64(count))
+	for i := 0; i < count; i++ {
+		w.uvarint(4)
+		w.Write([]byte{10, byte(i), 0, 0})
+		w.uvarint(4)
+		w.Write([]byte{10, byte(i), 255, 255})
+	}
+}
+
+// testSRSBody 生成包含 3 个域名、2 个关键字、2 个端口、1 个正则和 2 个 IP 段的规则集内容
+func testSRSBody() []byte {
+	var w srsWriter
+	w.uvarint(2)
+
+	// 普通规则
+	w.WriteByte(srsRuleTypeDefault)
+	w.WriteByte(srsItemDomain)
+	w.domains(3)
+	w.WriteByte(srsItemDomainKeyword)
+	w.strings("google", "youtube")
+	w.WriteByte(srsItemPort)
+	w.uvarint(2)
+	binary.Write(&w, binary.BigEndian, []uint16{80, 443})
+	w.WriteByte(srsItemFinal)
+	w.WriteByte(0)
+
+	// 逻辑规则
+	w.WriteByte(srsRuleTypeLogical)
+	w.WriteByte(0)
+	w.uvarint(2)
+	w.WriteByte(srsRuleTypeDefault)
+	w.WriteByte(srsItemDomainRegex)
+	w.strings(`^ad\.`)
+	w.WriteByte(srsItemFinal)
+	w.WriteByte(0)
+	w.WriteByte(srsRuleTypeDefault)
+	w.WriteByte(srsItemIPCIDR)
+	w.ipCIDRs(2)
+	w.WriteByte(srsItemFinal)
+	w.WriteByte(1)
+	w.WriteByte(0)
+	return w.Bytes()
+}
+
+func compressSRS(t *testing.T, body []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func buildSRS(version byte, body []byte) []byte {
+	return append([]byte{'S', 'R', 'S', version}, body...)
+}
+
+func TestCountSRSRules(t *testing.T) {
+	body := testSRSBody()
+	compressed := compressSRS(t, body)
+	valid := buildSRS(1, compressed)
+	tests := []struct {
+		name    string
+		data    []byte
+		want    int
+		wantErr bool
+	}{
+		{name: "valid", data: valid, want: 10},
+		{name: "version 3", data: buildSRS(3, compressed), want: 10},
+		{name: "empty rule set", data: buildSRS(1, compressSRS(t, []byte{0})), want: 0},
+		{name: "truncated file", data: valid[:len(valid)/2], wantErr: true},
+		{name: "truncated body", data: buildSRS(1, compressSRS(t, body[:len(body)-3])), wantErr: true},
+		{name: "header only", data: []byte("SRS"), wantErr: true},
+		{name: "bad magic", data: append([]byte("SRX\x01"), compressed...), wantErr: true},
+		{name: "version 0", data: buildSRS(0, compressed), wantErr: true},
+		{name: "unknown version", data: buildSRS(srsMaxVersion+1, compressed), wantErr: true},
+		// 规则集内容必须经过 zlib 压缩
+		{name: "raw body", data: buildSRS(1, body), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CountSRSRules(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CountSRSRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CountSRSRules() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -125,6 +125,13 @@ func (u *Updater) updateOne(ruleSet *models.RuleSet) error {
 		return fmt.Errorf("failed to update rule set: %v", err)
 	}
 
+	// 广告规则集更新后同步规则数量
+	if ruleSet.ID == AdBlockRuleSetTag {
+		if err := RefreshAdBlockStats(u.storage); err != nil {
+			u.logger.Warnf("Failed to refresh ad block stats: %v", err)
+		}
+	}
+
 	u.logger.Infof("Updated rule set %s successfully", ruleSet.ID)
 	return nil
 }
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -20,6 +22,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -56,6 +59,8 @@ type Server struct {
 	updater      *ruleset.Updater
 	networkStats *NetworkStats // Add network stats cache
 	proxy        *proxy.Manager
+	history      *config.History
+	configMu     sync.Mutex // 串行化数据修改与配置生成
 }
 
 // NewServer creates a new API server
@@ -73,7 +78,7 @@ func NewServer(storage storage.Storage, manager *proxy.Manager, logger *logrus.L
 		config := cors.Config{
 			AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
 			AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-CSRF-Token"},
-			ExposeHeaders:    []string{"Content-Length", "Content-Type", "Authorization"},
+			ExposeHeaders:    []string{"Content-Length", "Content-Type", "Authorization", configCheckedHeader},
 			AllowCredentials: true,
 			MaxAge:           12 * time.Hour,
 			AllowOriginFunc: func(origin string) bool {
@@ -97,6 +102,7 @@ func NewServer(storage storage.Storage, manager *proxy.Manager, logger *logrus.L
 		logger:       logger,
 		config:       cfg,
 		networkStats: &NetworkStats{Timestamp: time.Now()},
+		history:      config.NewHistory(storage, filepath.Join("configs", "sing-box", "config.json"), config.DefaultBinPath),
 		proxy:        manager,
 	}
 
@@ -130,6 +136,11 @@ func (s *Server) Start() error {
 		return fmt.Errorf("failed to initialize rule sets: %v", err)
 	}
 
+	// 统计广告规则数量
+	if err := ruleset.RefreshAdBlockStats(s.storage); err != nil {
+		s.logger.Warnf("Failed to refresh ad block stats: %v", err)
+	}
+
 	// 启动服务器
 	return s.router.Run()
 }
@@ -153,6 +164,19 @@ func (s *Server) setupRoutes() {
 	s.router.POST("/api/system/services/:name/start", s.handleStartService)
 	s.router.POST("/api/system/services/:name/stop", s.handleStopService)
 	s.router.POST("/api/system/services/:name/restart", s.handleRestartService)
+	s.router.POST("/api/system/services/:name/reload", s.handleReloadService)
+
+	// 备份还原
+	s.router.GET("/api/backup", s.handleExportBackup)
+	s.router.POST("/api/backup/restore", s.handleRestoreBackup)
+
+	// 配置版本
+	s.router.GET("/api/config/versions", s.handleGetConfigVersions)
+	s.router.POST("/api/config/versions", s.handleCreateConfigVersion)
+	s.router.GET("/api/config/versions/:id", s.handleGetConfigVersion)
+	s.router.DELETE("/api/config/versions/:id", s.handleDeleteConfigVersion)
+	s.router.GET("/api/config/versions/:id/diff", s.handleDiffConfigVersions)
+	s.router.POST("/api/config/versions/:id/rollback", s.handleRollbackConfigVersion)
 
 	// Node routes
 	s.router.GET("/api/nodes", s.handleGetNodes)
@@ -169,6 +193,12 @@ func (s *Server) setupRoutes() {
 	s.router.PUT("/api/node-groups/:id", s.handleUpdateNodeGroup)
 	s.router.DELETE("/api/node-groups/:id", s.handleDeleteNodeGroup)
 
+	// 策略组
+	s.router.GET("/api/policy-groups", s.handleGetPolicyGroups)
+	s.router.POST("/api/policy-groups", s.handleCreatePolicyGroup)
+	s.router.PUT("/api/policy-groups/:id", s.handleUpdatePolicyGroup)
+	s.router.DELETE("/api/policy-groups/:id", s.handleDeletePolicyGroup)
+
 	// Rule routes
 	s.router.GET("/api/rules", s.handleGetRules)
 	s.router.GET("/api/rules/:id", s.handleGetRule)
@@ -203,6 +233,10 @@ func (s *Server) setupRoutes() {
 	s.router.PUT("/api/dns/rules/:id", s.handleUpdateDNSRule)
 	s.router.DELETE("/api/dns/rules/:id", s.handleDeleteDNSRule)
 	s.router.PUT("/api/dns/settings", s.handleUpdateDNSSettings)
+	s.router.GET("/api/dns/servers", s.handleGetDNSServers)
+	s.router.POST("/api/dns/servers", s.handleCreateDNSServer)
+	s.router.PUT("/api/dns/servers/:id", s.handleUpdateDNSServer)
+	s.router.DELETE("/api/dns/servers/:id", s.handleDeleteDNSServer)
 }
 
 // handleGetSystemInfo handles GET /api/system/info
@@ -309,12 +343,19 @@ func (s *Server) handleGetSystemInfo(c *gin.Context) {
 // handleGetSystemStatus handles GET /api/system/status
 func (s *Server) handleGetSystemStatus(c *gin.Context) {
 	// Get services status
+	status := s.manager.GetStatus()
 	services := []gin.H{
 		{
-			"name":       "sing-box",
-			"is_running": s.manager.IsRunning(),
-			"version":    s.manager.GetVersion(),
-			"uptime":     s.manager.GetUptime(),
+			"name":              "sing-box",
+			"is_running":        status.State == proxy.ProcessStateRunning,
+			"version":           s.manager.GetVersion(),
+			"uptime":            s.manager.GetUptime(),
+			"state":             status.State,
+			"pid":               status.PID,
+			"restart_count":     status.RestartCount,
+			"last_exit_code":    status.LastExitCode,
+			"last_crash_reason": status.LastCrashReason,
+			"last_crash_at":     status.LastCrashAt,
 		},
 	}
 
@@ -428,20 +469,27 @@ func (s *Server) handleCreateNode(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := node.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 设置新节点的 ID 和时间戳
 	node.ID = uuid.New().String()
 	node.CreatedAt = time.Now()
 	node.UpdatedAt = time.Now()
 
-	if err := s.storage.SaveNode(&node); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := s.checkDetours(&node, nil); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("Failed to regenerate config: %v", err)
+	// 保存并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.SaveNode(&node)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
+		return
 	}
 
 	c.JSON(http.StatusOK, node)
@@ -466,19 +514,26 @@ func (s *Server) handleUpdateNode(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := node.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 设置节点 ID 和更新时间
 	node.ID = id
 	node.UpdatedAt = time.Now()
 
-	if err := s.storage.SaveNode(&node); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := s.checkDetours(&node, nil); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("Failed to regenerate config: %v", err)
+	// 保存并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.SaveNode(&node)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
+		return
 	}
 
 	c.JSON(http.StatusOK, node)
@@ -487,14 +542,18 @@ func (s *Server) handleUpdateNode(c *gin.Context) {
 // handleDeleteNode handles DELETE /api/nodes/:id
 func (s *Server) handleDeleteNode(c *gin.Context) {
 	id := c.Param("id")
-	if err := s.storage.DeleteNode(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	if node, err := s.storage.GetNodeByID(id); err == nil {
+		if err := s.checkDetourUnused(id, node.Name); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 	}
-
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("Failed to regenerate config: %v", err)
+	// 删除并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.DeleteNode(id)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
+		return
 	}
 
 	c.Status(http.StatusNoContent)
@@ -523,20 +582,27 @@ func (s *Server) handleCreateNodeGroup(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := group.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 设置新节点组的 ID 和时间戳
 	group.ID = uuid.New().String()
 	group.CreatedAt = time.Now()
 	group.UpdatedAt = time.Now()
 
-	if err := s.storage.SaveNodeGroup(&group); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := s.checkDetours(nil, &group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("Failed to regenerate config: %v", err)
+	// 保存并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.SaveNodeGroup(&group)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
+		return
 	}
 
 	c.JSON(http.StatusOK, group)
@@ -561,19 +627,26 @@ func (s *Server) handleUpdateNodeGroup(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := group.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 设置节点组 ID 和更新时间
 	group.ID = id
 	group.UpdatedAt = time.Now()
 
-	if err := s.storage.SaveNodeGroup(&group); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := s.checkDetours(nil, &group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("Failed to regenerate config: %v", err)
+	// 保存并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.SaveNodeGroup(&group)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
+		return
 	}
 
 	c.JSON(http.StatusOK, group)
@@ -582,14 +655,122 @@ func (s *Server) handleUpdateNodeGroup(c *gin.Context) {
 // handleDeleteNodeGroup handles DELETE /api/node-groups/:id
 func (s *Server) handleDeleteNodeGroup(c *gin.Context) {
 	id := c.Param("id")
-	if err := s.storage.DeleteNodeGroup(id); err != nil {
+	if group, err := s.storage.GetNodeGroupByID(id); err == nil {
+		if err := s.checkDetourUnused(id, group.Name); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	// 删除并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.DeleteNodeGroup(id)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleGetPolicyGroups handles GET /api/policy-groups
+func (s *Server) handleGetPolicyGroups(c *gin.Context) {
+	groups, err := s.storage.GetPolicyGroups()
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("Failed to regenerate config: %v", err)
+	c.JSON(http.StatusOK, groups)
+}
+
+// handleCreatePolicyGroup handles POST /api/policy-groups
+func (s *Server) handleCreatePolicyGroup(c *gin.Context) {
+	var group models.PolicyGroup
+	if err := c.ShouldBindJSON(&group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 设置策略组 ID
+	group.ID = uuid.New().String()
+
+	if err := s.validatePolicyGroup(&group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 保存并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.SavePolicyGroup(&group)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// handleUpdatePolicyGroup handles PUT /api/policy-groups/:id
+func (s *Server) handleUpdatePolicyGroup(c *gin.Context) {
+	id := c.Param("id")
+	existing, err := s.storage.GetPolicyGroupByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "policy group not found"})
+		return
+	}
+
+	var group models.PolicyGroup
+	if err := c.ShouldBindJSON(&group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	group.ID = id
+	group.CreatedAt = existing.CreatedAt
+
+	if err := s.validatePolicyGroup(&group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 修改名称前确认旧名称没有被引用
+	if group.Name != existing.Name || group.Tag() != existing.Tag() {
+		if err := s.checkOutboundUnused(existing.Name, existing.Tag()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 保存并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.SavePolicyGroup(&group)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// handleDeletePolicyGroup handles DELETE /api/policy-groups/:id
+func (s *Server) handleDeletePolicyGroup(c *gin.Context) {
+	id := c.Param("id")
+	group, err := s.storage.GetPolicyGroupByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "policy group not found"})
+		return
+	}
+
+	if err := s.checkOutboundUnused(group.ID, group.Name, group.Tag()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 删除并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.DeletePolicyGroup(id)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
+		return
 	}
 
 	c.Status(http.StatusNoContent)
@@ -634,15 +815,17 @@ func (s *Server) handleCreateRule(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported rule type: %s", rule.Type)})
 		return
 	}
-
-	if err := s.storage.SaveRule(&rule); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := s.checkRuleOutbound(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("Failed to regenerate config: %v", err)
+	// 保存并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.SaveRule(&rule)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
+		return
 	}
 
 	c.JSON(http.StatusOK, rule)
@@ -688,33 +871,45 @@ func (s *Server) handleUpdateRule(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported rule type: %s", rule.Type)})
 		return
 	}
-
-	if err := s.storage.SaveRule(&rule); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := s.checkRuleOutbound(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("Failed to regenerate config: %v", err)
+	// 保存并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.SaveRule(&rule)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
+		return
 	}
 
 	c.JSON(http.StatusOK, rule)
 }
 
+// checkRuleOutbound 使用与生成配置时相同的出站解析规则检查规则的出站是否存在
+func (s *Server) checkRuleOutbound(rule *models.Rule) error {
+	_, ok, err := config.NewSingBoxGenerator(s.storage).ResolveOutbound(rule.Outbound)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("rule %s: unknown outbound %s", rule.Name, rule.Outbound)
+	}
+	return nil
+}
+
 // handleDeleteRule handles DELETE /api/rules/:id
 func (s *Server) handleDeleteRule(c *gin.Context) {
 	id := c.Param("id")
-	if err := s.storage.DeleteRule(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// 删除并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.DeleteRule(id)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
 		return
 	}
 
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("Failed to regenerate config: %v", err)
-	}
-
 	c.Status(http.StatusNoContent)
 }
 
@@ -798,15 +993,11 @@ func (s *Server) handleCreateRuleSet(c *gin.Context) {
 		}
 	}
 
-	if err := s.storage.SaveRuleSet(&ruleSet); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("重新生成配置文件失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("重新生成配置文件失败: %v", err)})
+	// 保存并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.SaveRuleSet(&ruleSet)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
 		return
 	}
 
@@ -880,27 +1071,18 @@ func (s *Server) handleUpdateRuleSet(c *gin.Context) {
 				return
 			}
 		}
-
-		if err := s.storage.SaveRuleSet(&ruleSet); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
 	} else {
 		// 如果规则集存在，更新现有规则集
 		ruleSet.ID = id
 		ruleSet.UpdatedAt = time.Now()
 		ruleSet.Path = existingRuleSet.Path // 保持原有的文件路径
-
-		if err := s.storage.SaveRuleSet(&ruleSet); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
 	}
 
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("重新生成配置文件失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("重新生成配置文件失败: %v", err)})
+	// 保存并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.SaveRuleSet(&ruleSet)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
 		return
 	}
 
@@ -917,15 +1099,11 @@ func (s *Server) handleDeleteRuleSet(c *gin.Context) {
 		s.logger.Warnf("删除规则文件失败: %v", err)
 	}
 
-	if err := s.storage.DeleteRuleSet(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("重新生成配置文件失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("重新生成配置文件失败: %v", err)})
+	// 删除并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.DeleteRuleSet(id)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
 		return
 	}
 
@@ -977,17 +1155,21 @@ func (s *Server) handleUpdateRuleSetRules(c *gin.Context) {
 		return
 	}
 
-	// 更新规则集时间戳
+	// 更新规则集时间戳并重新生成配置文件，配置未生效时撤销修改
 	ruleSet.UpdatedAt = time.Now()
-	if err := s.storage.SaveRuleSet(ruleSet); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to update rule set: %v", err)})
-		return
-	}
-
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("重新生成配置文件失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("重新生成配置文件失败: %v", err)})
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		if err := store.SaveRuleSet(ruleSet); err != nil {
+			return fmt.Errorf("failed to update rule set: %v", err)
+		}
+		// 广告规则集更新后同步规则数量
+		if ruleSet.ID == ruleset.AdBlockRuleSetTag {
+			if err := ruleset.RefreshAdBlockStats(store); err != nil {
+				s.logger.Warnf("Failed to refresh ad block stats: %v", err)
+			}
+		}
+		return nil
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
 		return
 	}
 
@@ -1115,7 +1297,7 @@ func min(a, b int) int {
 }
 
 // refreshSubscription refreshes a subscription
-func (s *Server) refreshSubscription(sub *models.Subscription) error {
+func (s *Server) refreshSubscription(sub *models.Subscription, user string) error {
 	s.logger.Infof("开始刷新订阅: %s (%s)", sub.Name, sub.ID)
 	s.logger.Debugf("订阅详情: url=%s, active=%v", sub.URL, sub.Active)
 
@@ -1158,21 +1340,7 @@ func (s *Server) refreshSubscription(sub *models.Subscription) error {
 
 	s.logger.Infof("成功解析 %d 个节点", len(nodes))
 
-	// 更新订阅信息
-	sub.LastUpdate = time.Now()
-	sub.NodeCount = len(nodes)
-	sub.Type = detectedType // 更新订阅类型为检测到的类型
-	if err := s.storage.SaveSubscription(sub); err != nil {
-		return fmt.Errorf("保存订阅信息失败: %v", err)
-	}
-
-	// 删除旧节点
-	if err := s.storage.DeleteNodesBySubscriptionID(sub.ID); err != nil {
-		s.logger.Errorf("删除旧节点失败: %v", err)
-		return fmt.Errorf("删除旧节点失败: %v", err)
-	}
-
-	// 添加新节点
+	// 整理新节点
 	for _, node := range nodes {
 		// URL 解码节点名称
 		if decodedName, err := url.QueryUnescape(node.Name); err == nil {
@@ -1185,7 +1353,7 @@ func (s *Server) refreshSubscription(sub *models.Subscription) error {
 		}
 
 		// 设置节点的订阅 ID 和其他必要字段
-		node.ID = "" // 确保是新节点，这样会触发自动匹配逻辑
+		node.ID = ""
 		node.SubscriptionID = sub.ID
 		node.CreatedAt = time.Now()
 		node.UpdatedAt = time.Now()
@@ -1201,24 +1369,94 @@ func (s *Server) refreshSubscription(sub *models.Subscription) error {
 			node.Latency = 0
 		}
 		node.CheckedAt = time.Now()
+	}
 
-		// 保存节点
-		if err := s.storage.SaveNode(node); err != nil {
-			s.logger.Errorf("保存节点失败: %v", err)
-			return fmt.Errorf("保存节点失败: %v", err)
+	// 替换节点并重新生成配置文件，配置未生效时保留原有节点
+	_, err = s.applyConfigChange(models.ConfigTriggerSubscription, user, func(store storage.Storage) error {
+		// 更新订阅信息
+		sub.LastUpdate = time.Now()
+		sub.NodeCount = len(nodes)
+		sub.Type = detectedType // 更新订阅类型为检测到的类型
+		if err := store.SaveSubscription(sub); err != nil {
+			return fmt.Errorf("保存订阅信息失败: %v", err)
 		}
-	}
 
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("重新生成配置文件失败: %v", err)
-		return fmt.Errorf("重新生成配置文件失败: %v", err)
+		// 沿用旧节点的 ID，保持出站标签和代理链引用不变
+		if err := s.keepSubscriptionNodeIDs(store, sub.ID, nodes); err != nil {
+			return fmt.Errorf("获取旧节点失败: %v", err)
+		}
+
+		// 删除旧节点
+		if err := store.DeleteNodesBySubscriptionID(sub.ID); err != nil {
+			s.logger.Errorf("删除旧节点失败: %v", err)
+			return fmt.Errorf("删除旧节点失败: %v", err)
+		}
+
+		// 添加新节点
+		for _, node := range nodes {
+			if err := store.SaveNode(node); err != nil {
+				s.logger.Errorf("保存节点失败: %v", err)
+				return fmt.Errorf("保存节点失败: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Errorf("刷新订阅失败: %v", err)
+		return err
 	}
 
 	s.logger.Infof("订阅刷新完成，成功添加 %d 个节点", len(nodes))
 	return nil
 }
 
+// keepSubscriptionNodeIDs 为刷新后的节点沿用同一服务器和凭据的旧节点的 ID，
+// 多个旧节点的服务器和凭据相同时优先沿用名称相同的，没有对应旧节点的保持 ID 为空，保存时生成新 ID
+func (s *Server) keepSubscriptionNodeIDs(store storage.Storage, subscriptionID string, nodes []*models.Node) error {
+	existing, err := store.GetNodes()
+	if err != nil {
+		return err
+	}
+
+	oldNodes := make(map[string][]models.Node)
+	for _, node := range existing {
+		if node.SubscriptionID == subscriptionID {
+			key := subscriptionNodeKey(&node)
+			oldNodes[key] = append(oldNodes[key], node)
+		}
+	}
+
+	// keep 沿用第 i 个候选旧节点的 ID，同一旧节点只沿用一次
+	keep := func(node *models.Node, key string, i int) {
+		old := oldNodes[key][i]
+		node.ID = old.ID
+		node.CreatedAt = old.CreatedAt
+		oldNodes[key] = append(oldNodes[key][:i], oldNodes[key][i+1:]...)
+	}
+	// 先匹配名称相同的旧节点，再按顺序匹配剩余的
+	for _, node := range nodes {
+		key := subscriptionNodeKey(node)
+		for i, old := range oldNodes[key] {
+			if old.Name == node.Name {
+				keep(node, key, i)
+				break
+			}
+		}
+	}
+	for _, node := range nodes {
+		key := subscriptionNodeKey(node)
+		if node.ID == "" && len(oldNodes[key]) > 0 {
+			keep(node, key, 0)
+		}
+	}
+	return nil
+}
+
+// subscriptionNodeKey 订阅节点的匹配键，由协议、服务器地址、端口和凭据组成
+func subscriptionNodeKey(node *models.Node) string {
+	return fmt.Sprintf("%s://%s:%s@%s:%d/%s", node.Type, node.UUID, node.Password, node.Address, node.Port, node.Method)
+}
+
 // handleCreateSubscription handles POST /api/subscriptions
 func (s *Server) handleCreateSubscription(c *gin.Context) {
 	var subscription models.Subscription
@@ -1251,7 +1489,7 @@ func (s *Server) handleCreateSubscription(c *gin.Context) {
 
 	// 如果订阅是激活的，即刷新一次
 	if subscription.Active {
-		if err := s.refreshSubscription(&subscription); err != nil {
+		if err := s.refreshSubscription(&subscription, c.GetString("username")); err != nil {
 			s.logger.Warnf("Failed to refresh subscription: %v", err)
 		}
 	}
@@ -1306,7 +1544,7 @@ func (s *Server) handleUpdateSubscription(c *gin.Context) {
 
 	// 如果订阅是激活的，即刷新一次
 	if subscription.Active {
-		if err := s.refreshSubscription(&subscription); err != nil {
+		if err := s.refreshSubscription(&subscription, c.GetString("username")); err != nil {
 			s.logger.Warnf("Failed to refresh subscription: %v", err)
 		}
 	}
@@ -1408,7 +1646,7 @@ func (s *Server) startSubscriptionUpdater() {
 					continue
 				}
 
-				if err := s.refreshSubscription(&subscriptions[i]); err != nil {
+				if err := s.refreshSubscription(&subscriptions[i], ""); err != nil {
 					s.logger.Errorf("Failed to refresh subscription %s: %v", subscriptions[i].ID, err)
 				}
 			}
@@ -1425,7 +1663,7 @@ func (s *Server) handleRefreshSubscription(c *gin.Context) {
 		return
 	}
 
-	if err := s.refreshSubscription(subscription); err != nil {
+	if err := s.refreshSubscription(subscription, c.GetString("username")); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to refresh subscription: %v", err)})
 		return
 	}
@@ -1530,9 +1768,33 @@ func (s *Server) handleUpdateSettings(c *gin.Context) {
 	var req struct {
 		ThemeMode   string                      `json:"theme_mode"`
 		SingboxMode *models.SingboxModeSettings `json:"singbox_mode"`
-		DNS         *models.DNSSettings         `json:"dns"`
+		DNS         json.RawMessage             `json:"dns"`
 		Dashboard   *models.DashboardSettings   `json:"dashboard"`
 		InboundMode string                      `json:"inbound_mode"`
+		EnableIPv6  *bool                       `json:"enable_ipv6"`
+
+		// 入站设置
+		ProxyPort       *int    `json:"proxy_port"`
+		RedirectPort    *int    `json:"redirect_port"`
+		TProxyPort      *int    `json:"tproxy_port"`
+		DNSPort         *int    `json:"dns_port"`
+		ListenAddress   *string `json:"listen_address"`
+		TunInet4Address *string `json:"tun_inet4_address"`
+		TunInet6Address *string `json:"tun_inet6_address"`
+		TunStack        *string `json:"tun_stack"`
+		RoutingMark     *int    `json:"routing_mark"`
+		RouteTable      *int    `json:"route_table"`
+		DNSInbound      *string `json:"dns_inbound"`
+
+		// 节点组默认测速设置
+		URLTestURL       *string `json:"url_test_url"`
+		URLTestInterval  *string `json:"url_test_interval"`
+		URLTestTolerance *int    `json:"url_test_tolerance"`
+
+		// 保留的配置版本数
+		ConfigHistory *int `json:"config_history"`
+
+		InboundOptions *models.InboundOptionsSettings `json:"inbound_options"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -1564,6 +1826,76 @@ func (s *Server) handleUpdateSettings(c *gin.Context) {
 		}
 	}
 
+	if req.EnableIPv6 != nil {
+		settings.EnableIPv6 = *req.EnableIPv6
+	}
+
+	if req.ProxyPort != nil {
+		settings.ProxyPort = *req.ProxyPort
+	}
+	if req.RedirectPort != nil {
+		settings.RedirectPort = *req.RedirectPort
+	}
+	if req.TProxyPort != nil {
+		settings.TProxyPort = *req.TProxyPort
+	}
+	if req.DNSPort != nil {
+		settings.DNSPort = *req.DNSPort
+	}
+	if req.ListenAddress != nil {
+		settings.ListenAddress = *req.ListenAddress
+	}
+	if req.TunInet4Address != nil {
+		settings.TunInet4Address = *req.TunInet4Address
+	}
+	if req.TunInet6Address != nil {
+		settings.TunInet6Address = *req.TunInet6Address
+	}
+	if req.TunStack != nil {
+		settings.TunStack = *req.TunStack
+	}
+	if req.RoutingMark != nil {
+		settings.RoutingMark = *req.RoutingMark
+	}
+	if req.RouteTable != nil {
+		settings.RouteTable = *req.RouteTable
+	}
+	if req.DNSInbound != nil {
+		settings.DNSInbound = *req.DNSInbound
+	}
+	if req.InboundOptions != nil {
+		if err := settings.SetInboundOptions(req.InboundOptions); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := settings.ValidateInbound(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.URLTestURL != nil {
+		settings.URLTestURL = *req.URLTestURL
+	}
+	if req.URLTestInterval != nil {
+		settings.URLTestInterval = *req.URLTestInterval
+	}
+	if req.URLTestTolerance != nil {
+		settings.URLTestTolerance = *req.URLTestTolerance
+	}
+	if err := settings.ValidateURLTest(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.ConfigHistory != nil {
+		settings.ConfigHistory = *req.ConfigHistory
+	}
+	if err := settings.ValidateConfigHistory(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	if req.Dashboard != nil {
 		if err := settings.SetDashboard(req.Dashboard); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -1571,24 +1903,41 @@ func (s *Server) handleUpdateSettings(c *gin.Context) {
 		}
 	}
 
-	if req.DNS != nil {
-		// 保存 DNS 设置
-		if err := s.storage.SaveDNSSettings(req.DNS); err != nil {
+	var dnsSettings *models.DNSSettings
+	if len(req.DNS) > 0 {
+		// 在现有 DNS 设置基础上合并提交的字段
+		dnsSettings, err = s.storage.GetDNSSettings()
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-	}
+		id := dnsSettings.ID
+		if err := json.Unmarshal(req.DNS, dnsSettings); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		dnsSettings.ID = id
 
-	// 保存设置
-	if err := s.storage.UpdateSettings(settings); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		if err := dnsSettings.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := s.checkDNSFinal(dnsSettings); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("重新生成配置文件失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("重新生成配置文件失败: %v", err)})
+	// 保存设置并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		if dnsSettings != nil {
+			if err := store.SaveDNSSettings(dnsSettings); err != nil {
+				return err
+			}
+		}
+		return store.UpdateSettings(settings)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
 		return
 	}
 
@@ -1632,49 +1981,367 @@ func (s *Server) handleRestartService(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("%s restarted successfully", name)})
 }
 
-// regenerateConfig regenerates the sing-box config file
-func (s *Server) regenerateConfig() error {
-	// 生成 sing-box 配置
-	generator := config.NewSingBoxGenerator(s.storage)
-	data, err := generator.GenerateConfig()
-	if err != nil {
-		return fmt.Errorf("generate sing-box config: %w", err)
-	}
-
-	// 确保配置目录存在
-	configDir := filepath.Join("configs", "sing-box")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return fmt.Errorf("create config directory: %w", err)
+// handleReloadService handles POST /api/system/services/:name/reload
+func (s *Server) handleReloadService(c *gin.Context) {
+	name := c.Param("name")
+	if err := s.manager.ReloadService(name); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("%s reloaded successfully", name)})
+}
 
-	// 写入配置文件
-	configPath := filepath.Join(configDir, "config.json")
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
-		return fmt.Errorf("write config file: %w", err)
+// handleGetConfigVersions handles GET /api/config/versions
+func (s *Server) handleGetConfigVersions(c *gin.Context) {
+	versions, err := s.storage.GetConfigVersions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	s.logger.Infof("sing-box config file generated: %s", configPath)
-	return nil
+	c.JSON(http.StatusOK, versions)
 }
 
-// handleGetDNSRules handles GET /api/dns/rules
-func (s *Server) handleGetDNSRules(c *gin.Context) {
-	// 获取 DNS 规则
-	rules, err := s.storage.GetDNSRules()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+// handleCreateConfigVersion handles POST /api/config/versions
+func (s *Server) handleCreateConfigVersion(c *gin.Context) {
+	if err := s.generateConfig(models.ConfigTriggerManual, c.GetString("username")); err != nil {
+		s.logger.Errorf("重新生成配置文件失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("重新生成配置文件失败: %v", err)})
 		return
 	}
 
-	// 获取 DNS 设置
-	settings, err := s.storage.GetDNSSettings()
+	version, err := s.storage.GetLatestConfigVersion()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	version.Content = ""
 
-	c.JSON(http.StatusOK, gin.H{
-		"rules":    rules,
+	c.JSON(http.StatusOK, version)
+}
+
+// handleGetConfigVersion handles GET /api/config/versions/:id
+func (s *Server) handleGetConfigVersion(c *gin.Context) {
+	version, err := s.storage.GetConfigVersionByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "config version not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, version)
+}
+
+// handleDeleteConfigVersion handles DELETE /api/config/versions/:id
+func (s *Server) handleDeleteConfigVersion(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := s.storage.GetConfigVersionByID(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "config version not found"})
+		return
+	}
+
+	if err := s.storage.DeleteConfigVersion(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleDiffConfigVersions handles GET /api/config/versions/:id/diff?to=:id
+// to 为空时与最新版本比较
+func (s *Server) handleDiffConfigVersions(c *gin.Context) {
+	to := c.Query("to")
+	if to == "" {
+		latest, err := s.storage.GetLatestConfigVersion()
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "config version not found"})
+			return
+		}
+		to = latest.ID
+	}
+
+	diff, err := s.history.Diff(c.Param("id"), to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from": c.Param("id"),
+		"to":   to,
+		"diff": diff,
+	})
+}
+
+// handleRollbackConfigVersion handles POST /api/config/versions/:id/rollback
+// 回滚是临时的：只改写配置文件，下次重新生成配置时按数据库内容覆盖
+func (s *Server) handleRollbackConfigVersion(c *gin.Context) {
+	var req struct {
+		Restart bool `json:"restart"` // 重启 sing-box，否则重新加载配置
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	id := c.Param("id")
+	if _, err := s.storage.GetConfigVersionByID(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "config version not found"})
+		return
+	}
+
+	// 与数据修改和配置生成串行，避免回滚的配置被同时生成的配置覆盖
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	version, err := s.history.Rollback(id, c.GetString("username"))
+	if err != nil {
+		s.logger.Errorf("回滚配置失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("回滚配置失败: %v", err)})
+		return
+	}
+	version.Content = ""
+
+	if s.manager.IsRunning() {
+		if req.Restart {
+			err = s.manager.RestartService("sing-box")
+		} else {
+			err = s.manager.Reload()
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "已临时回滚配置文件。节点、规则等数据没有回滚，下次修改设置或自动更新订阅、规则集时将按当前数据重新生成配置，回滚失效",
+		"temporary": true,
+		"version":   version,
+	})
+}
+
+// handleExportBackup handles GET /api/backup
+func (s *Server) handleExportBackup(c *gin.Context) {
+	backup, err := s.storage.ExportBackup()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("singdns-backup-%s.json", backup.CreatedAt.Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.JSON(http.StatusOK, backup)
+}
+
+// handleRestoreBackup handles POST /api/backup/restore?mode=merge|replace
+// 备份可以作为请求体提交，也可以通过表单字段 file 上传
+func (s *Server) handleRestoreBackup(c *gin.Context) {
+	mode := c.DefaultQuery("mode", models.RestoreModeMerge)
+
+	var backup models.Backup
+	if c.ContentType() == "multipart/form-data" {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer f.Close()
+		if err := json.NewDecoder(f).Decode(&backup); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid backup file: %v", err)})
+			return
+		}
+	} else if err := c.ShouldBindJSON(&backup); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := backup.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if mode != models.RestoreModeMerge && mode != models.RestoreModeReplace {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid restore mode: %s", mode)})
+		return
+	}
+
+	// 恢复并重新生成配置文件，配置未生效时撤销恢复
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		if err := store.ImportBackup(&backup, mode); err != nil {
+			s.logger.Errorf("恢复备份失败: %v", err)
+			return fmt.Errorf("恢复备份失败: %v", err)
+		}
+		return nil
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "backup restored successfully",
+		"mode":           mode,
+		"schema_version": backup.SchemaVersion,
+	})
+}
+
+// configCheckedHeader 响应头，表示重新生成的配置是否通过了 sing-box check
+const configCheckedHeader = "X-Config-Checked"
+
+// configChangeError 数据修改后配置无法生成或未通过检查，修改已撤销
+type configChangeError struct {
+	err error
+}
+
+// Error implements error
+func (e *configChangeError) Error() string {
+	return fmt.Sprintf("配置未生效，修改已撤销: %v", e.err)
+}
+
+// Unwrap returns the underlying error
+func (e *configChangeError) Unwrap() error {
+	return e.err
+}
+
+// commitConfigChange applies a data change for an API request and regenerates the config
+func (s *Server) commitConfigChange(c *gin.Context, change func(store storage.Storage) error) error {
+	version, err := s.applyConfigChange(configTrigger(c), c.GetString("username"), change)
+	if err != nil {
+		return err
+	}
+	// 告知调用方配置未经 sing-box check 检查
+	c.Header(configCheckedHeader, strconv.FormatBool(version.Checked))
+	return nil
+}
+
+// applyConfigChange 在同一事务中执行数据修改并重新生成配置，修改失败或配置无法生成、
+// 未通过 sing-box check 时回滚事务，保证数据库与正在使用的配置文件一致
+func (s *Server) applyConfigChange(trigger, user string, change func(store storage.Storage) error) (*models.ConfigVersion, error) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	var version *models.ConfigVersion
+	var configErr error
+	err := s.storage.Transaction(func(tx storage.Storage) error {
+		if err := change(tx); err != nil {
+			return err
+		}
+		var err error
+		if version, err = s.writeConfig(tx, trigger, user); err != nil {
+			configErr = err
+			return err
+		}
+		return nil
+	})
+	if configErr != nil {
+		return nil, &configChangeError{err: configErr}
+	}
+	if err != nil {
+		if version != nil {
+			s.logger.Errorf("配置文件已更新，但保存数据失败: %v", err)
+		}
+		return nil, err
+	}
+
+	s.reloadConfig()
+	return version, nil
+}
+
+// respondConfigChangeError writes the response for a failed commitConfigChange
+func (s *Server) respondConfigChangeError(c *gin.Context, err error) {
+	var changeErr *configChangeError
+	if !errors.As(err, &changeErr) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 请求的数据无法生成可用的配置
+	s.logger.Errorf("重新生成配置文件失败: %v", err)
+	c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+}
+
+// configTrigger 根据请求的 API 资源确定配置版本的触发来源，如 /api/nodes/:id 为 nodes
+func configTrigger(c *gin.Context) string {
+	resource, _, _ := strings.Cut(strings.TrimPrefix(c.FullPath(), "/api/"), "/")
+	switch resource {
+	case "settings":
+		return models.ConfigTriggerSettings
+	case "subscriptions":
+		return models.ConfigTriggerSubscription
+	case "":
+		return models.ConfigTriggerManual
+	default:
+		return resource
+	}
+}
+
+// generateConfig regenerates the sing-box config file and records it as a new version
+func (s *Server) generateConfig(trigger, user string) error {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	if _, err := s.writeConfig(s.storage, trigger, user); err != nil {
+		return err
+	}
+	s.reloadConfig()
+	return nil
+}
+
+// writeConfig 根据 store 中的数据生成配置，通过 sing-box check 后替换配置文件并记录为新版本
+func (s *Server) writeConfig(store storage.Storage, trigger, user string) (*models.ConfigVersion, error) {
+	// 生成 sing-box 配置
+	generator := config.NewSingBoxGenerator(store)
+	data, err := generator.GenerateConfig()
+	if err != nil {
+		return nil, fmt.Errorf("generate sing-box config: %w", err)
+	}
+
+	// 通过 sing-box check 后替换配置文件，失败时保留上一次的配置
+	version, err := s.history.WithStorage(store).Apply(data, trigger, user)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("sing-box config file generated, version %s (%s)", version.ID, trigger)
+	if !version.Checked {
+		s.logger.Warnf("sing-box binary %s not found, config version %s was not checked", config.DefaultBinPath, version.ID)
+	}
+	return version, nil
+}
+
+// reloadConfig 运行中的 sing-box 重新加载配置，不中断进程
+func (s *Server) reloadConfig() {
+	if s.manager.IsRunning() {
+		if err := s.manager.Reload(); err != nil {
+			s.logger.WithError(err).Warn("Failed to reload sing-box config")
+		}
+	}
+}
+
+// handleGetDNSRules handles GET /api/dns/rules
+func (s *Server) handleGetDNSRules(c *gin.Context) {
+	// 获取 DNS 规则
+	rules, err := s.storage.GetDNSRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 获取 DNS 设置
+	settings, err := s.storage.GetDNSSettings()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rules":    rules,
 		"settings": settings,
 	})
 }
@@ -1692,19 +2359,19 @@ func (s *Server) handleCreateDNSRule(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := s.checkDNSServerTag(rule.Action); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 设置规则 ID
 	rule.ID = uuid.New().String()
 
-	if err := s.storage.SaveDNSRule(&rule); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("重新生成配置文件失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("重新生成配置文件失败: %v", err)})
+	// 保存并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.SaveDNSRule(&rule)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
 		return
 	}
 
@@ -1725,19 +2392,19 @@ func (s *Server) handleUpdateDNSRule(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := s.checkDNSServerTag(rule.Action); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 设置规则 ID
 	rule.ID = id
 
-	if err := s.storage.SaveDNSRule(&rule); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("重新生成配置文件失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("重新生成配置文件失败: %v", err)})
+	// 保存并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.SaveDNSRule(&rule)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
 		return
 	}
 
@@ -1747,15 +2414,11 @@ func (s *Server) handleUpdateDNSRule(c *gin.Context) {
 // handleDeleteDNSRule handles DELETE /api/dns/rules/:id
 func (s *Server) handleDeleteDNSRule(c *gin.Context) {
 	id := c.Param("id")
-	if err := s.storage.DeleteDNSRule(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("重新生成配置文件失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("重新生成配置文件失败: %v", err)})
+	// 删除并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.DeleteDNSRule(id)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
 		return
 	}
 
@@ -1764,25 +2427,430 @@ func (s *Server) handleDeleteDNSRule(c *gin.Context) {
 
 // handleUpdateDNSSettings handles PUT /api/dns/settings
 func (s *Server) handleUpdateDNSSettings(c *gin.Context) {
-	var settings models.DNSSettings
-	if err := c.ShouldBindJSON(&settings); err != nil {
+	// 在现有设置基础上更新，未提交的字段保持不变
+	settings, err := s.storage.GetDNSSettings()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	id := settings.ID
+
+	if err := c.ShouldBindJSON(settings); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	settings.ID = id
 
-	if err := s.storage.SaveDNSSettings(&settings); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// 验证设置
+	if err := settings.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.checkDNSFinal(settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 重新生成配置文件
-	if err := s.regenerateConfig(); err != nil {
-		s.logger.Errorf("重新生成配置文件失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("重新生成配置文件失败: %v", err)})
+	// 保存并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.SaveDNSSettings(settings)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, settings)
 }
 
+// handleGetDNSServers handles GET /api/dns/servers
+func (s *Server) handleGetDNSServers(c *gin.Context) {
+	servers, err := s.storage.GetDNSServers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, servers)
+}
+
+// handleCreateDNSServer handles POST /api/dns/servers
+func (s *Server) handleCreateDNSServer(c *gin.Context) {
+	var server models.DNSServer
+	if err := c.ShouldBindJSON(&server); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 设置服务器 ID
+	server.ID = uuid.New().String()
+
+	if err := s.validateDNSServer(&server); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 保存并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.SaveDNSServer(&server)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, server)
+}
+
+// handleUpdateDNSServer handles PUT /api/dns/servers/:id
+func (s *Server) handleUpdateDNSServer(c *gin.Context) {
+	id := c.Param("id")
+	existing, err := s.storage.GetDNSServerByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "dns server not found"})
+		return
+	}
+
+	var server models.DNSServer
+	if err := c.ShouldBindJSON(&server); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	server.ID = id
+	server.CreatedAt = existing.CreatedAt
+
+	if err := s.validateDNSServer(&server); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 修改标签前确认旧标签没有被引用
+	if server.Tag != existing.Tag {
+		if err := s.checkDNSServerUnused(existing.Tag); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 保存并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.SaveDNSServer(&server)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, server)
+}
+
+// handleDeleteDNSServer handles DELETE /api/dns/servers/:id
+func (s *Server) handleDeleteDNSServer(c *gin.Context) {
+	id := c.Param("id")
+	server, err := s.storage.GetDNSServerByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "dns server not found"})
+		return
+	}
+
+	if err := s.checkDNSServerUnused(server.Tag); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 删除并重新生成配置文件，配置未生效时撤销修改
+	if err := s.commitConfigChange(c, func(store storage.Storage) error {
+		return store.DeleteDNSServer(id)
+	}); err != nil {
+		s.respondConfigChangeError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// validateDNSServer 验证 DNS 服务器字段以及标签唯一性和解析服务器引用
+func (s *Server) validateDNSServer(server *models.DNSServer) error {
+	if err := server.Validate(); err != nil {
+		return err
+	}
+
+	servers, err := s.storage.GetDNSServers()
+	if err != nil {
+		return err
+	}
+	for _, other := range servers {
+		if other.ID != server.ID && other.Tag == server.Tag {
+			return fmt.Errorf("dns server tag %s already exists", server.Tag)
+		}
+	}
+
+	if server.AddressResolver != "" {
+		if err := s.checkDNSAddressResolver(server.AddressResolver, servers); err != nil {
+			return err
+		}
+	}
+
+	// 代理链使用与生成配置时相同的出站解析规则
+	if server.Detour != "" {
+		_, ok, err := config.NewSingBoxGenerator(s.storage).ResolveOutbound(server.Detour)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("dns server %s: unknown detour %s", server.Tag, server.Detour)
+		}
+	}
+	return nil
+}
+
+// checkDNSAddressResolver 检查解析服务器是否为国内、远程 DNS 或已有的自定义服务器
+func (s *Server) checkDNSAddressResolver(tag string, servers []models.DNSServer) error {
+	switch tag {
+	case "alidns", "google":
+		return nil
+	}
+	for _, server := range servers {
+		if server.Tag == tag {
+			return nil
+		}
+	}
+	return fmt.Errorf("dns server %s cannot be used as address resolver", tag)
+}
+
+// checkDNSFinal 检查 DNS 设置的 Final 是否为生成配置时可以使用的服务器
+// 拦截和系统 DNS 不能作为最终服务器，FakeIP 只能在启用时使用
+func (s *Server) checkDNSFinal(settings *models.DNSSettings) error {
+	switch settings.Final {
+	case "direct", "domestic", "remote", "alidns", "google":
+		return nil
+	case "fakeip":
+		if !settings.EnableFakeIP {
+			return fmt.Errorf("fakeip cannot be used as final server when fakeip is disabled")
+		}
+		return nil
+	case "block", "dns-block", "dns-local":
+		return fmt.Errorf("dns server %s cannot be used as final server", settings.Final)
+	}
+	return s.checkDNSServerTag(settings.Final)
+}
+
+// checkDNSServerTag 检查 DNS 规则动作或 Final 引用的服务器是否存在，FakeIP 只能在启用时使用
+func (s *Server) checkDNSServerTag(tag string) error {
+	switch tag {
+	case "direct", "remote", "block", "domestic":
+		return nil
+	case "fakeip":
+		settings, err := s.storage.GetDNSSettings()
+		if err != nil {
+			return err
+		}
+		if !settings.EnableFakeIP {
+			return fmt.Errorf("fakeip cannot be used when fakeip is disabled")
+		}
+		return nil
+	}
+	if models.IsBuiltinDNSServerTag(tag) {
+		return nil
+	}
+
+	servers, err := s.storage.GetDNSServers()
+	if err != nil {
+		return err
+	}
+	for _, server := range servers {
+		if server.Tag == tag {
+			return nil
+		}
+	}
+	return fmt.Errorf("dns server %s not found", tag)
+}
+
+// validatePolicyGroup 验证策略组字段、名称唯一性以及候选出站
+func (s *Server) validatePolicyGroup(group *models.PolicyGroup) error {
+	if err := group.Validate(); err != nil {
+		return err
+	}
+
+	groups, err := s.storage.GetPolicyGroups()
+	if err != nil {
+		return err
+	}
+	for _, other := range groups {
+		if other.ID == group.ID {
+			continue
+		}
+		if other.Name == group.Name || other.Tag() == group.Tag() {
+			return fmt.Errorf("policy group %s already exists", group.Name)
+		}
+		// 策略组之间不能互相引用，避免选择器形成循环
+		for _, outbound := range group.Outbounds {
+			if outbound == other.ID || outbound == other.Name || outbound == other.Tag() {
+				return fmt.Errorf("policy group %s cannot use policy group %s as outbound", group.Name, other.Name)
+			}
+		}
+	}
+
+	// 标签不能与内置出站和节点组重名
+	switch group.Tag() {
+	case models.PolicyOutboundProxy, "direct-out", "block", "dns-out":
+		return fmt.Errorf("policy group name %s is reserved", group.Tag())
+	}
+	nodeGroups, err := s.storage.GetNodeGroups()
+	if err != nil {
+		return err
+	}
+	for _, nodeGroup := range nodeGroups {
+		if nodeGroup.Name == group.Tag() {
+			return fmt.Errorf("policy group name %s conflicts with node group", group.Tag())
+		}
+	}
+	return nil
+}
+
+// checkOutboundUnused 检查出站是否仍被规则、规则集或 DNS 服务器引用，names 为出站的 ID、名称或标签
+func (s *Server) checkOutboundUnused(names ...string) error {
+	used := func(outbound string) bool {
+		for _, name := range names {
+			if outbound == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	rules, err := s.storage.GetRules()
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if used(rule.Outbound) {
+			return fmt.Errorf("%s is used by rule %s", names[0], rule.Name)
+		}
+	}
+
+	ruleSets, err := s.storage.GetRuleSets()
+	if err != nil {
+		return err
+	}
+	for _, ruleSet := range ruleSets {
+		if used(ruleSet.Outbound) {
+			return fmt.Errorf("%s is used by rule set %s", names[0], ruleSet.Name)
+		}
+	}
+
+	servers, err := s.storage.GetDNSServers()
+	if err != nil {
+		return err
+	}
+	for _, server := range servers {
+		if used(server.Detour) {
+			return fmt.Errorf("%s is used as detour by dns server %s", names[0], server.Tag)
+		}
+	}
+	return nil
+}
+
+// checkDetours 使用待保存的节点或节点组替换数据库中的记录后检查代理链
+func (s *Server) checkDetours(node *models.Node, group *models.NodeGroup) error {
+	nodes, err := s.storage.GetNodes()
+	if err != nil {
+		return err
+	}
+	groups, err := s.storage.GetNodeGroups()
+	if err != nil {
+		return err
+	}
+
+	if node != nil {
+		replaced := false
+		for i := range nodes {
+			if nodes[i].ID == node.ID {
+				nodes[i] = *node
+				replaced = true
+			}
+		}
+		if !replaced {
+			nodes = append(nodes, *node)
+		}
+	}
+	if group != nil {
+		replaced := false
+		for i := range groups {
+			if groups[i].ID == group.ID {
+				groups[i] = *group
+				replaced = true
+			}
+		}
+		if !replaced {
+			groups = append(groups, *group)
+		}
+	}
+
+	return models.CheckDetours(nodes, groups)
+}
+
+// checkDetourUnused 检查节点或节点组是否仍被其他节点、节点组或 DNS 服务器用作代理链目标
+func (s *Server) checkDetourUnused(id, name string) error {
+	nodes, err := s.storage.GetNodes()
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if node.ID != id && node.Detour != "" && (node.Detour == id || node.Detour == name) {
+			return fmt.Errorf("%s is used as detour by node %s", name, node.Name)
+		}
+	}
+
+	groups, err := s.storage.GetNodeGroups()
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if group.ID != id && group.Detour != "" && (group.Detour == id || group.Detour == name) {
+			return fmt.Errorf("%s is used as detour by node group %s", name, group.Name)
+		}
+	}
+
+	servers, err := s.storage.GetDNSServers()
+	if err != nil {
+		return err
+	}
+	for _, server := range servers {
+		if server.Detour != "" && (server.Detour == id || server.Detour == name) {
+			return fmt.Errorf("%s is used as detour by dns server %s", name, server.Tag)
+		}
+	}
+	return nil
+}
+
+// checkDNSServerUnused 检查 DNS 服务器是否仍被规则、设置或其他服务器引用
+func (s *Server) checkDNSServerUnused(tag string) error {
+	rules, err := s.storage.GetDNSRules()
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if rule.Action == tag {
+			return fmt.Errorf("dns server %s is used by dns rule %s", tag, rule.Value)
+		}
+	}
+
+	settings, err := s.storage.GetDNSSettings()
+	if err != nil {
+		return err
+	}
+	if settings.Final == tag {
+		return fmt.Errorf("dns server %s is used as final server", tag)
+	}
+
+	servers, err := s.storage.GetDNSServers()
+	if err != nil {
+		return err
+	}
+	for _, server := range servers {
+		if server.Tag != tag && server.AddressResolver == tag {
+			return fmt.Errorf("dns server %s is used as address resolver by %s", tag, server.Tag)
+		}
+	}
+	return nil
+}
+
 // ... rest of the code ...
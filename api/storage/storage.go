@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"singdns/api/models"
+	"slices"
 	"strings"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
@@ -68,10 +70,37 @@ type Storage interface {
 	SaveDNSRule(rule *models.DNSRule) error
 	DeleteDNSRule(id string) error
 
+	// DNS 服务器
+	GetDNSServers() ([]models.DNSServer, error)
+	GetDNSServerByID(id string) (*models.DNSServer, error)
+	SaveDNSServer(server *models.DNSServer) error
+	DeleteDNSServer(id string) error
+
+	// 策略组
+	GetPolicyGroups() ([]models.PolicyGroup, error)
+	GetPolicyGroupByID(id string) (*models.PolicyGroup, error)
+	SavePolicyGroup(group *models.PolicyGroup) error
+	DeletePolicyGroup(id string) error
+
+	// 配置版本
+	GetConfigVersions() ([]models.ConfigVersion, error)
+	GetConfigVersionByID(id string) (*models.ConfigVersion, error)
+	GetLatestConfigVersion() (*models.ConfigVersion, error)
+	SaveConfigVersion(version *models.ConfigVersion) error
+	DeleteConfigVersion(id string) error
+	PruneConfigVersions(keep int) error
+
+	// 备份
+	ExportBackup() (*models.Backup, error)
+	ImportBackup(backup *models.Backup, mode string) error
+
 	// DNS 设置
 	GetDNSSettings() (*models.DNSSettings, error)
 	SaveDNSSettings(settings *models.DNSSettings) error
 
+	// 事务：fn 中通过 tx 进行的读写在同一事务中执行，fn 返回错误时全部回滚
+	Transaction(fn func(tx Storage) error) error
+
 	// Close database connection
 	Close() error
 }
@@ -82,6 +111,13 @@ type SQLiteStorage struct {
 	logger *logrus.Logger
 }
 
+// Transaction runs fn with a storage bound to a single database transaction
+func (s *SQLiteStorage) Transaction(fn func(tx Storage) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&SQLiteStorage{db: tx, logger: s.logger})
+	})
+}
+
 // GetDB returns the underlying database connection
 func (s *SQLiteStorage) GetDB() *gorm.DB {
 	return s.db
@@ -118,7 +154,8 @@ func NewSQLiteStorage(dbPath string) (Storage, error) {
 	}
 
 	// Open database
-	db, err := gorm.Open(sqlite.Open(dbPath), config)
+	// 事务开始时即获取写锁，其他写入等待事务结束，等待时间覆盖配置检查的超时时间
+	db, err := gorm.Open(sqlite.Open(dbPath+"?_txlock=immediate&_busy_timeout=35000"), config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %v", err)
 	}
@@ -134,6 +171,10 @@ func NewSQLiteStorage(dbPath string) (Storage, error) {
 		&models.User{},
 		&models.DNSRule{},
 		&models.DNSSettings{},
+		&models.DNSServer{},
+		&models.PolicyGroup{},
+		&models.ConfigVersion{},
+		&models.Migration{},
 	); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %v", err)
 	}
@@ -197,6 +238,13 @@ func NewSQLiteStorage(dbPath string) (Storage, error) {
 		}
 	}
 
+	// 只在首次启动时创建默认策略组，用户删除后不再重新创建
+	if err := runMigration(db, "seed-default-policy-groups", func(tx *gorm.DB) error {
+		return seedDefaultPolicyGroups(tx, logger)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create default policy groups: %v", err)
+	}
+
 	// Create default node groups if not exist
 	defaultGroups := []models.NodeGroup{
 		{
@@ -385,9 +433,171 @@ func NewSQLiteStorage(dbPath string) (Storage, error) {
 		logger.Infof("Created default node group: %s", group.Name)
 	}
 
+	// 节点组不再生成 "X自动" 出站，引用它们的规则改为使用节点组本身
+	if err := runMigration(db, "rename-auto-node-group-outbounds", func(tx *gorm.DB) error {
+		return migrateAutoNodeGroupOutbounds(tx, logger)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to migrate auto node group outbounds: %v", err)
+	}
+
+	// 早期版本默认 DNS 入站端口为 53，改为新的默认端口
+	if err := runMigration(db, "default-dns-port-5353", func(tx *gorm.DB) error {
+		return migrateDefaultDNSPort(tx, logger)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to migrate default dns port: %v", err)
+	}
+
 	return storage, nil
 }
 
+// runMigration 执行一次性数据迁移，已执行过的迁移直接跳过
+func runMigration(db *gorm.DB, id string, migrate func(tx *gorm.DB) error) error {
+	var count int64
+	if err := db.Model(&models.Migration{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	// 开始事务
+	tx := db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := migrate(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Create(&models.Migration{ID: id}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// seedDefaultPolicyGroups 策略组为空时创建默认策略组，已有策略组的旧数据库保持不变
+func seedDefaultPolicyGroups(tx *gorm.DB, logger *logrus.Logger) error {
+	var count int64
+	if err := tx.Model(&models.PolicyGroup{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	for _, group := range models.DefaultPolicyGroups() {
+		if err := tx.Create(&group).Error; err != nil {
+			return fmt.Errorf("create %s: %v", group.Name, err)
+		}
+	}
+	logger.Info("Created default policy groups")
+	return nil
+}
+
+// migrateDefaultDNSPort 将仍为旧默认值 53 的 DNS 入站端口改为 DefaultDNSPort
+// 无法区分旧默认值和手动设置的 53，逐条记录修改过的设置，需要时可在设置中改回
+func migrateDefaultDNSPort(tx *gorm.DB, logger *logrus.Logger) error {
+	var settings []models.Settings
+	if err := tx.Where("dns_port = ?", 53).Find(&settings).Error; err != nil {
+		return err
+	}
+	for _, setting := range settings {
+		if err := tx.Model(&setting).Update("dns_port", models.DefaultDNSPort).Error; err != nil {
+			return err
+		}
+		logger.Warnf("Settings %s: changed DNS port from old default 53 to %d, change it back in settings if 53 was intended", setting.ID, models.DefaultDNSPort)
+	}
+	return nil
+}
+
+// migrateAutoNodeGroupOutbounds 将规则、规则集、策略组和 DNS 服务器中引用的 "X自动" 出站改为节点组 X
+func migrateAutoNodeGroupOutbounds(tx *gorm.DB, logger *logrus.Logger) error {
+	var groups []models.NodeGroup
+	if err := tx.Find(&groups).Error; err != nil {
+		return err
+	}
+	renamed := make(map[string]string, len(groups))
+	for _, group := range groups {
+		renamed[group.Name+"自动"] = group.Name
+	}
+
+	var rules []models.Rule
+	if err := tx.Find(&rules).Error; err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if name, ok := renamed[rule.Outbound]; ok {
+			logger.Warnf("Rule %s: outbound %s no longer exists, use %s", rule.Name, rule.Outbound, name)
+			if err := tx.Model(&rule).Update("outbound", name).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	var ruleSets []models.RuleSet
+	if err := tx.Find(&ruleSets).Error; err != nil {
+		return err
+	}
+	for _, ruleSet := range ruleSets {
+		if name, ok := renamed[ruleSet.Outbound]; ok {
+			logger.Warnf("Rule set %s: outbound %s no longer exists, use %s", ruleSet.Name, ruleSet.Outbound, name)
+			if err := tx.Model(&ruleSet).Update("outbound", name).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	var policyGroups []models.PolicyGroup
+	if err := tx.Find(&policyGroups).Error; err != nil {
+		return err
+	}
+	for _, group := range policyGroups {
+		changed := false
+		outbounds := make(models.StringArray, 0, len(group.Outbounds))
+		for _, outbound := range group.Outbounds {
+			if name, ok := renamed[outbound]; ok {
+				logger.Warnf("Policy group %s: outbound %s no longer exists, use %s", group.Name, outbound, name)
+				outbound = name
+				changed = true
+			}
+			// 节点组和对应的自动组同时存在时去重
+			if !slices.Contains(outbounds, outbound) {
+				outbounds = append(outbounds, outbound)
+			}
+		}
+		if name, ok := renamed[group.Default]; ok {
+			group.Default = name
+			changed = true
+		}
+		if changed {
+			group.Outbounds = outbounds
+			if err := tx.Save(&group).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	var servers []models.DNSServer
+	if err := tx.Find(&servers).Error; err != nil {
+		return err
+	}
+	for _, server := range servers {
+		if name, ok := renamed[server.Detour]; ok {
+			logger.Warnf("DNS server %s: detour %s no longer exists, use %s", server.Tag, server.Detour, name)
+			if err := tx.Model(&server).Update("detour", name).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // Node operations
 func (s *SQLiteStorage) GetNodes() ([]models.Node, error) {
 	var nodes []models.Node
@@ -452,170 +662,145 @@ func (s *SQLiteStorage) GetNodesWithPagination(page, pageSize int) ([]models.Nod
 }
 
 func (s *SQLiteStorage) SaveNode(node *models.Node) error {
-	// 开始事务
-	tx := s.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 如果是新建节点
+		isNewNode := node.ID == ""
+		if isNewNode {
+			node.ID = uuid.New().String()
 		}
-	}()
 
-	// 如果是新建节点
-	isNewNode := node.ID == ""
-	if isNewNode {
-		node.ID = uuid.New().String()
-	}
+		// 如果是更新现有节点，获取原有节点数据
+		var existingNode models.Node
+		if !isNewNode {
+			err := tx.First(&existingNode, "id = ?", node.ID).Error
+			if err != nil && err != gorm.ErrRecordNotFound {
+				return err
+			}
+			// 如果找到了现有节点，且新节点的 CheckedAt 为空，保留原有的 CheckedAt
+			if err == nil && node.CheckedAt.IsZero() {
+				node.CheckedAt = existingNode.CheckedAt
+			}
+		}
 
-	// 如果是更新现有节点，获取原有节点数据
-	var existingNode models.Node
-	if !isNewNode {
-		err := tx.First(&existingNode, "id = ?", node.ID).Error
-		if err != nil && err != gorm.ErrRecordNotFound {
-			tx.Rollback()
+		// 保存节点基本信息
+		if err := tx.Save(node).Error; err != nil {
 			return err
 		}
-		// 如果找到了现有节点，且新节点的 CheckedAt 为空，保留原有的 CheckedAt
-		if err == nil && node.CheckedAt.IsZero() {
-			node.CheckedAt = existingNode.CheckedAt
-		}
-	}
-
-	// 保存节点基本信息
-	if err := tx.Save(node).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
 
-	// 获取所有节点组
-	var groups []models.NodeGroup
-	if err := tx.Find(&groups).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	// 遍历所有节点组，检查是否匹配
-	var matchedGroups []models.NodeGroup
-	for _, group := range groups {
-		// "全部"组特殊处理
-		if group.Name == "全部 🌏" {
-			matchedGroups = append(matchedGroups, group)
-			continue
+		// 获取所有节点组
+		var groups []models.NodeGroup
+		if err := tx.Find(&groups).Error; err != nil {
+			return err
 		}
 
-		// 检查包含规则
-		matched := false
-		if len(group.IncludePatterns) > 0 {
-			for _, pattern := range group.IncludePatterns {
-				pattern = strings.TrimSpace(pattern)
-				if pattern == "" {
-					continue
-				}
+		// 遍历所有节点组，检查是否匹配
+		var matchedGroups []models.NodeGroup
+		for _, group := range groups {
+			// "全部"组特殊处理
+			if group.Name == "全部 🌏" {
+				matchedGroups = append(matchedGroups, group)
+				continue
+			}
 
-				// 尝试编译正则表达式
-				re, err := regexp.Compile(pattern)
-				if err == nil {
-					// 如果是有效的正则表达式，使用正则匹配
-					if re.MatchString(node.Name) {
-						matched = true
-						break
+			// 检查包含规则
+			matched := false
+			if len(group.IncludePatterns) > 0 {
+				for _, pattern := range group.IncludePatterns {
+					pattern = strings.TrimSpace(pattern)
+					if pattern == "" {
+						continue
 					}
-				} else {
-					// 如果不是有效的正则表达式，使用关键字匹配
-					if strings.Contains(strings.ToLower(node.Name), strings.ToLower(pattern)) {
-						matched = true
-						break
+
+					// 尝试编译正则表达式
+					re, err := regexp.Compile(pattern)
+					if err == nil {
+						// 如果是有效的正则表达式，使用正则匹配
+						if re.MatchString(node.Name) {
+							matched = true
+							break
+						}
+					} else {
+						// 如果不是有效的正则表达式，使用关键字匹配
+						if strings.Contains(strings.ToLower(node.Name), strings.ToLower(pattern)) {
+							matched = true
+							break
+						}
 					}
 				}
+			} else {
+				matched = true // 如果没有包含规则，默认匹配
 			}
-		} else {
-			matched = true // 如果没有包含规则，默认匹配
-		}
-
-		// 检查排除规则
-		if matched && len(group.ExcludePatterns) > 0 {
-			for _, pattern := range group.ExcludePatterns {
-				pattern = strings.TrimSpace(pattern)
-				if pattern == "" {
-					continue
-				}
 
-				// 尝试编译正则表达式
-				re, err := regexp.Compile(pattern)
-				if err == nil {
-					// 如果是有效的正则表达式，使用正则匹配
-					if re.MatchString(node.Name) {
-						matched = false
-						break
+			// 检查排除规则
+			if matched && len(group.ExcludePatterns) > 0 {
+				for _, pattern := range group.ExcludePatterns {
+					pattern = strings.TrimSpace(pattern)
+					if pattern == "" {
+						continue
 					}
-				} else {
-					// 如果不是有效的正则表达式，使用关键字匹配
-					if strings.Contains(strings.ToLower(node.Name), strings.ToLower(pattern)) {
-						matched = false
-						break
+
+					// 尝试编译正则表达式
+					re, err := regexp.Compile(pattern)
+					if err == nil {
+						// 如果是有效的正则表达式，使用正则匹配
+						if re.MatchString(node.Name) {
+							matched = false
+							break
+						}
+					} else {
+						// 如果不是有效的正则表达式，使用关键字匹配
+						if strings.Contains(strings.ToLower(node.Name), strings.ToLower(pattern)) {
+							matched = false
+							break
+						}
 					}
 				}
 			}
-		}
 
-		if matched {
-			matchedGroups = append(matchedGroups, group)
+			if matched {
+				matchedGroups = append(matchedGroups, group)
+			}
 		}
-	}
 
-	// 更新节点组关联
-	if err := tx.Model(node).Association("NodeGroups").Replace(&matchedGroups); err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	// 更新每个匹配组的节点数量
-	for _, group := range matchedGroups {
-		var count int64
-		count = tx.Model(&group).Association("Nodes").Count()
-		if err := tx.Model(&group).Update("node_count", count).Error; err != nil {
-			tx.Rollback()
+		// 更新节点组关联
+		if err := tx.Model(node).Association("NodeGroups").Replace(&matchedGroups); err != nil {
 			return err
 		}
-	}
 
-	return tx.Commit().Error
+		// 更新每个匹配组的节点数量
+		for _, group := range matchedGroups {
+			var count int64
+			count = tx.Model(&group).Association("Nodes").Count()
+			if err := tx.Model(&group).Update("node_count", count).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 }
 
 func (s *SQLiteStorage) DeleteNode(id string) error {
-	// 开始事务
-	tx := s.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		s.logger.Infof("开始删除节点 ID: %s", id)
 
-	s.logger.Infof("开始删除节点 ID: %s", id)
-
-	// 先删除节点与节点组的关联关系
-	if err := tx.Exec("DELETE FROM node_group_nodes WHERE node_id = ?", id).Error; err != nil {
-		s.logger.Errorf("删除节点关联关系失败: %v", err)
-		tx.Rollback()
-		return err
-	}
-	s.logger.Infof("已删除节点的组关联关系")
+		// 先删除节点与节点组的关联关系
+		if err := tx.Exec("DELETE FROM node_group_nodes WHERE node_id = ?", id).Error; err != nil {
+			s.logger.Errorf("删除节点关联关系失败: %v", err)
+			return err
+		}
+		s.logger.Infof("已删除节点的组关联关系")
 
-	// 删除节点
-	result := tx.Delete(&models.Node{}, "id = ?", id)
-	if result.Error != nil {
-		s.logger.Errorf("删除节点失败: %v", result.Error)
-		tx.Rollback()
-		return result.Error
-	}
-	s.logger.Infof("已删除节点，影响行数: %d", result.RowsAffected)
+		// 删除节点
+		result := tx.Delete(&models.Node{}, "id = ?", id)
+		if result.Error != nil {
+			s.logger.Errorf("删除节点失败: %v", result.Error)
+			return result.Error
+		}
+		s.logger.Infof("已删除节点，影响行数: %d", result.RowsAffected)
 
-	return tx.Commit().Error
+		return nil
+	})
 }
 
 // Rule operations
@@ -636,50 +821,38 @@ func (s *SQLiteStorage) GetRuleByID(id string) (*models.Rule, error) {
 }
 
 func (s *SQLiteStorage) SaveRule(rule *models.Rule) error {
-	// 开始事务
-	tx := s.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// 检查是否已存在同ID的规则
-	var existingRule models.Rule
-	err := tx.First(&existingRule, "id = ?", rule.ID).Error
-	if err != nil && err != gorm.ErrRecordNotFound {
-		tx.Rollback()
-		return err
-	}
-
-	// 如果规则已存在，更新现有规则
-	if err == nil {
-		// 使用 Updates 而不是 Save，以避免创建新记录
-		if err := tx.Model(&existingRule).Updates(map[string]interface{}{
-			"name":        rule.Name,
-			"type":        rule.Type,
-			"outbound":    rule.Outbound,
-			"description": rule.Description,
-			"enabled":     rule.Enabled,
-			"priority":    rule.Priority,
-			"values":      rule.Values,
-			"updated_at":  rule.UpdatedAt,
-		}).Error; err != nil {
-			tx.Rollback()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 检查是否已存在同ID的规则
+		var existingRule models.Rule
+		err := tx.First(&existingRule, "id = ?", rule.ID).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
 			return err
 		}
-	} else {
-		// 如果规则不存在，创建新规则
-		if err := tx.Create(rule).Error; err != nil {
-			tx.Rollback()
-			return err
+
+		// 如果规则已存在，更新现有规则
+		if err == nil {
+			// 使用 Updates 而不是 Save，以避免创建新记录
+			if err := tx.Model(&existingRule).Updates(map[string]interface{}{
+				"name":        rule.Name,
+				"type":        rule.Type,
+				"outbound":    rule.Outbound,
+				"description": rule.Description,
+				"enabled":     rule.Enabled,
+				"priority":    rule.Priority,
+				"values":      rule.Values,
+				"updated_at":  rule.UpdatedAt,
+			}).Error; err != nil {
+				return err
+			}
+		} else {
+			// 如果规则不存在，创建新规则
+			if err := tx.Create(rule).Error; err != nil {
+				return err
+			}
 		}
-	}
 
-	return tx.Commit().Error
+		return nil
+	})
 }
 
 func (s *SQLiteStorage) DeleteRule(id string) error {
@@ -711,45 +884,32 @@ func (s *SQLiteStorage) SaveSubscription(sub *models.Subscription) error {
 }
 
 func (s *SQLiteStorage) DeleteSubscription(id string) error {
-	// 开始事务
-	tx := s.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 获取该订阅下的所有节点
+		var nodes []models.Node
+		if err := tx.Where("subscription_id = ?", id).Find(&nodes).Error; err != nil {
+			return err
 		}
-	}()
 
-	// 获取该订阅下的所有节点
-	var nodes []models.Node
-	if err := tx.Where("subscription_id = ?", id).Find(&nodes).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
+		// 清除每个节点的节点组关联
+		for _, node := range nodes {
+			if err := tx.Model(&node).Association("NodeGroups").Clear(); err != nil {
+				return err
+			}
+		}
 
-	// 清除每个节点的节点组关联
-	for _, node := range nodes {
-		if err := tx.Model(&node).Association("NodeGroups").Clear(); err != nil {
-			tx.Rollback()
+		// 删除该订阅下的所有节点
+		if err := tx.Delete(&models.Node{}, "subscription_id = ?", id).Error; err != nil {
 			return err
 		}
-	}
 
-	// 删除该订阅下的所有节点
-	if err := tx.Delete(&models.Node{}, "subscription_id = ?", id).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	// 删除订阅
-	if err := tx.Delete(&models.Subscription{}, "id = ?", id).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
+		// 删除订阅
+		if err := tx.Delete(&models.Subscription{}, "id = ?", id).Error; err != nil {
+			return err
+		}
 
-	return tx.Commit().Error
+		return nil
+	})
 }
 
 // Settings operations
@@ -766,9 +926,9 @@ func (s *SQLiteStorage) GetSettings() (*models.Settings, error) {
 				ThemeMode:        "light",
 				Language:         "zh-CN",
 				UpdateInterval:   24,
-				ProxyPort:        7890,
+				ProxyPort:        models.DefaultProxyPort,
 				APIPort:          8080,
-				DNSPort:          53,
+				DNSPort:          models.DefaultDNSPort,
 				LogLevel:         "info",
 				EnableAutoUpdate: true,
 				UpdatedAt:        now,
@@ -884,165 +1044,136 @@ func (s *SQLiteStorage) GetNodeGroupByID(id string) (*models.NodeGroup, error) {
 }
 
 func (s *SQLiteStorage) SaveNodeGroup(group *models.NodeGroup) error {
-	// 开始事务
-	tx := s.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 如果是新分组生成ID
+		if group.ID == "" {
+			group.ID = uuid.New().String()
 		}
-	}()
 
-	// 如果是新分组生成ID
-	if group.ID == "" {
-		group.ID = uuid.New().String()
-	}
-
-	// 获取所有节点
-	var allNodes []models.Node
-	if err := tx.Find(&allNodes).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	s.logger.Infof("Processing node group %s with include patterns: %v, exclude patterns: %v", group.Name, group.IncludePatterns, group.ExcludePatterns)
-
-	// 匹配节点
-	var matchedNodes []models.Node
-	for _, node := range allNodes {
-		// 默认不匹配任何节点
-		matched := false
-
-		// "全部"分组特殊处理
-		if group.Name == "全部 🌏" {
-			matched = true
-		} else {
-			// 检查包含规则
-			if len(group.IncludePatterns) > 0 {
-				s.logger.Infof("Checking include patterns for node %s", node.Name)
-				for _, pattern := range group.IncludePatterns {
-					pattern = strings.TrimSpace(pattern)
-					if pattern == "" {
-						continue
-					}
+		// 获取所有节点
+		var allNodes []models.Node
+		if err := tx.Find(&allNodes).Error; err != nil {
+			return err
+		}
 
-					// 尝试编译正则表达式
-					re, err := regexp.Compile(pattern)
-					if err == nil {
-						// 如果是有效的正则表达式，使用正则匹配
-						if re.MatchString(node.Name) {
-							s.logger.Infof("Node %s matched regex pattern %s", node.Name, pattern)
-							matched = true
-							break
+		s.logger.Infof("Processing node group %s with include patterns: %v, exclude patterns: %v", group.Name, group.IncludePatterns, group.ExcludePatterns)
+
+		// 匹配节点
+		var matchedNodes []models.Node
+		for _, node := range allNodes {
+			// 默认不匹配任何节点
+			matched := false
+
+			// "全部"分组特殊处理
+			if group.Name == "全部 🌏" {
+				matched = true
+			} else {
+				// 检查包含规则
+				if len(group.IncludePatterns) > 0 {
+					s.logger.Infof("Checking include patterns for node %s", node.Name)
+					for _, pattern := range group.IncludePatterns {
+						pattern = strings.TrimSpace(pattern)
+						if pattern == "" {
+							continue
 						}
-					} else {
-						// 如果不是有效的正则表达式，使用关键字匹配
-						if strings.Contains(strings.ToLower(node.Name), strings.ToLower(pattern)) {
-							s.logger.Infof("Node %s matched keyword %s", node.Name, pattern)
-							matched = true
-							break
+
+						// 尝试编译正则表达式
+						re, err := regexp.Compile(pattern)
+						if err == nil {
+							// 如果是有效的正则表达式，使用正则匹配
+							if re.MatchString(node.Name) {
+								s.logger.Infof("Node %s matched regex pattern %s", node.Name, pattern)
+								matched = true
+								break
+							}
+						} else {
+							// 如果不是有效的正则表达式，使用关键字匹配
+							if strings.Contains(strings.ToLower(node.Name), strings.ToLower(pattern)) {
+								s.logger.Infof("Node %s matched keyword %s", node.Name, pattern)
+								matched = true
+								break
+							}
 						}
 					}
 				}
-			}
 
-			// 检查排除规则
-			if matched && len(group.ExcludePatterns) > 0 {
-				for _, pattern := range group.ExcludePatterns {
-					pattern = strings.TrimSpace(pattern)
-					if pattern == "" {
-						continue
-					}
-
-					// 尝试编译正则表达式
-					re, err := regexp.Compile(pattern)
-					if err == nil {
-						// 如果是有效的正则表达式，使用正则匹配
-						if re.MatchString(node.Name) {
-							s.logger.Infof("Node %s excluded by regex pattern %s", node.Name, pattern)
-							matched = false
-							break
+				// 检查排除规则
+				if matched && len(group.ExcludePatterns) > 0 {
+					for _, pattern := range group.ExcludePatterns {
+						pattern = strings.TrimSpace(pattern)
+						if pattern == "" {
+							continue
 						}
-					} else {
-						// 如果不是有效的正则表达式，使用关键字匹配
-						if strings.Contains(strings.ToLower(node.Name), strings.ToLower(pattern)) {
-							s.logger.Infof("Node %s excluded by keyword %s", node.Name, pattern)
-							matched = false
-							break
+
+						// 尝试编译正则表达式
+						re, err := regexp.Compile(pattern)
+						if err == nil {
+							// 如果是有效的正则表达式，使用正则匹配
+							if re.MatchString(node.Name) {
+								s.logger.Infof("Node %s excluded by regex pattern %s", node.Name, pattern)
+								matched = false
+								break
+							}
+						} else {
+							// 如果不是有效的正则表达式，使用关键字匹配
+							if strings.Contains(strings.ToLower(node.Name), strings.ToLower(pattern)) {
+								s.logger.Infof("Node %s excluded by keyword %s", node.Name, pattern)
+								matched = false
+								break
+							}
 						}
 					}
 				}
 			}
-		}
 
-		if matched {
-			s.logger.Infof("Adding node %s to group %s", node.Name, group.Name)
-			matchedNodes = append(matchedNodes, node)
-		} else {
-			s.logger.Infof("Node %s did not match group %s", node.Name, group.Name)
+			if matched {
+				s.logger.Infof("Adding node %s to group %s", node.Name, group.Name)
+				matchedNodes = append(matchedNodes, node)
+			} else {
+				s.logger.Infof("Node %s did not match group %s", node.Name, group.Name)
+			}
 		}
-	}
-
-	// 更新节点数量
-	group.NodeCount = len(matchedNodes)
 
-	// 保存分组信息
-	if err := tx.Save(group).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
+		// 更新节点数量
+		group.NodeCount = len(matchedNodes)
 
-	// 清除旧的节点关联
-	if err := tx.Model(group).Association("Nodes").Clear(); err != nil {
-		tx.Rollback()
-		return err
-	}
+		// 保存分组信息
+		if err := tx.Save(group).Error; err != nil {
+			return err
+		}
 
-	// 添加匹配的节点到分组
-	if len(matchedNodes) > 0 {
-		if err := tx.Model(group).Association("Nodes").Replace(&matchedNodes); err != nil {
-			tx.Rollback()
+		// 清除旧的节点关联
+		if err := tx.Model(group).Association("Nodes").Clear(); err != nil {
 			return err
 		}
-	}
 
-	s.logger.Infof("Node group %s updated with %d nodes", group.Name, group.NodeCount)
+		// 添加匹配的节点到分组
+		if len(matchedNodes) > 0 {
+			if err := tx.Model(group).Association("Nodes").Replace(&matchedNodes); err != nil {
+				return err
+			}
+		}
 
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
-		return err
-	}
+		s.logger.Infof("Node group %s updated with %d nodes", group.Name, group.NodeCount)
 
-	return nil
+		return nil
+	})
 }
 
 func (s *SQLiteStorage) DeleteNodeGroup(id string) error {
-	// 开始事务
-	tx := s.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 先清除节点组与节点关联关系
+		if err := tx.Exec("DELETE FROM node_group_nodes WHERE node_group_id = ?", id).Error; err != nil {
+			return err
 		}
-	}()
-
-	// 先清除节点组与节点关联关系
-	if err := tx.Exec("DELETE FROM node_group_nodes WHERE node_group_id = ?", id).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
 
-	// 删除节点组
-	if err := tx.Delete(&models.NodeGroup{}, "id = ?", id).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
+		// 删除节点组
+		if err := tx.Delete(&models.NodeGroup{}, "id = ?", id).Error; err != nil {
+			return err
+		}
 
-	return tx.Commit().Error
+		return nil
+	})
 }
 
 // RuleSet operations
@@ -1075,39 +1206,27 @@ func (s *SQLiteStorage) DeleteRuleSet(id string) error {
 
 // DeleteNodesBySubscriptionID deletes all nodes for a subscription
 func (s *SQLiteStorage) DeleteNodesBySubscriptionID(subscriptionID string) error {
-	// 开始事务
-	tx := s.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 获取该订阅下的所有节点ID
+		var nodeIDs []string
+		if err := tx.Model(&models.Node{}).Where("subscription_id = ?", subscriptionID).Pluck("id", &nodeIDs).Error; err != nil {
+			return err
 		}
-	}()
 
-	// 获取该订阅下的所有节点ID
-	var nodeIDs []string
-	if err := tx.Model(&models.Node{}).Where("subscription_id = ?", subscriptionID).Pluck("id", &nodeIDs).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
+		// 清除这些节点与节点组的关联关系
+		if len(nodeIDs) > 0 {
+			if err := tx.Exec("DELETE FROM node_group_nodes WHERE node_id IN (?)", nodeIDs).Error; err != nil {
+				return err
+			}
+		}
 
-	// 清除这些节点与节点组的关联关系
-	if len(nodeIDs) > 0 {
-		if err := tx.Exec("DELETE FROM node_group_nodes WHERE node_id IN (?)", nodeIDs).Error; err != nil {
-			tx.Rollback()
+		// 删除节点
+		if err := tx.Delete(&models.Node{}, "subscription_id = ?", subscriptionID).Error; err != nil {
 			return err
 		}
-	}
-
-	// 删除节点
-	if err := tx.Delete(&models.Node{}, "subscription_id = ?", subscriptionID).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
 
-	return tx.Commit().Error
+		return nil
+	})
 }
 
 // User operations
@@ -1173,6 +1292,297 @@ func (s *SQLiteStorage) DeleteDNSRule(id string) error {
 	return s.db.Delete(&models.DNSRule{}, "id = ?", id).Error
 }
 
+// GetDNSServers returns all DNS servers
+func (s *SQLiteStorage) GetDNSServers() ([]models.DNSServer, error) {
+	var servers []models.DNSServer
+	if err := s.db.Order("created_at").Find(&servers).Error; err != nil {
+		return nil, err
+	}
+	return servers, nil
+}
+
+// GetDNSServerByID returns a DNS server by ID
+func (s *SQLiteStorage) GetDNSServerByID(id string) (*models.DNSServer, error) {
+	var server models.DNSServer
+	if err := s.db.First(&server, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &server, nil
+}
+
+// SaveDNSServer saves a DNS server
+func (s *SQLiteStorage) SaveDNSServer(server *models.DNSServer) error {
+	return s.db.Save(server).Error
+}
+
+// DeleteDNSServer deletes a DNS server
+func (s *SQLiteStorage) DeleteDNSServer(id string) error {
+	return s.db.Delete(&models.DNSServer{}, "id = ?", id).Error
+}
+
+// GetPolicyGroups returns all policy groups
+func (s *SQLiteStorage) GetPolicyGroups() ([]models.PolicyGroup, error) {
+	var groups []models.PolicyGroup
+	if err := s.db.Order("priority").Order("created_at").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// GetPolicyGroupByID returns a policy group by ID
+func (s *SQLiteStorage) GetPolicyGroupByID(id string) (*models.PolicyGroup, error) {
+	var group models.PolicyGroup
+	if err := s.db.First(&group, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// SavePolicyGroup saves a policy group
+func (s *SQLiteStorage) SavePolicyGroup(group *models.PolicyGroup) error {
+	return s.db.Save(group).Error
+}
+
+// DeletePolicyGroup deletes a policy group
+func (s *SQLiteStorage) DeletePolicyGroup(id string) error {
+	return s.db.Delete(&models.PolicyGroup{}, "id = ?", id).Error
+}
+
+// GetConfigVersions returns all config versions without content, newest first
+func (s *SQLiteStorage) GetConfigVersions() ([]models.ConfigVersion, error) {
+	var versions []models.ConfigVersion
+	if err := s.db.Omit("content").Order("created_at desc").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// GetConfigVersionByID returns a config version by ID
+func (s *SQLiteStorage) GetConfigVersionByID(id string) (*models.ConfigVersion, error) {
+	var version models.ConfigVersion
+	if err := s.db.First(&version, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// GetLatestConfigVersion returns the most recent config version
+func (s *SQLiteStorage) GetLatestConfigVersion() (*models.ConfigVersion, error) {
+	var version models.ConfigVersion
+	if err := s.db.Order("created_at desc").First(&version).Error; err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// SaveConfigVersion saves a config version
+func (s *SQLiteStorage) SaveConfigVersion(version *models.ConfigVersion) error {
+	return s.db.Save(version).Error
+}
+
+// DeleteConfigVersion deletes a config version
+func (s *SQLiteStorage) DeleteConfigVersion(id string) error {
+	return s.db.Delete(&models.ConfigVersion{}, "id = ?", id).Error
+}
+
+// PruneConfigVersions keeps only the newest keep config versions
+func (s *SQLiteStorage) PruneConfigVersions(keep int) error {
+	keepIDs := s.db.Model(&models.ConfigVersion{}).Select("id").Order("created_at desc").Limit(keep)
+	return s.db.Where("id NOT IN (?)", keepIDs).Delete(&models.ConfigVersion{}).Error
+}
+
+// ExportBackup exports all configuration data
+func (s *SQLiteStorage) ExportBackup() (*models.Backup, error) {
+	backup := &models.Backup{
+		SchemaVersion: models.BackupSchemaVersion,
+		CreatedAt:     time.Now(),
+	}
+
+	// 节点组成员由匹配规则决定，不导出关联关系
+	tables := []interface{}{
+		&backup.Nodes,
+		&backup.NodeGroups,
+		&backup.PolicyGroups,
+		&backup.Rules,
+		&backup.RuleSets,
+		&backup.DNSRules,
+		&backup.DNSServers,
+		&backup.Subscriptions,
+	}
+	for _, rows := range tables {
+		if err := s.db.Find(rows).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var settings models.Settings
+	if err := s.db.First(&settings).Error; err == nil {
+		backup.Settings = &settings
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	var dnsSettings models.DNSSettings
+	if err := s.db.First(&dnsSettings).Error; err == nil {
+		backup.DNSSettings = &dnsSettings
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return backup, nil
+}
+
+// ImportBackup imports a backup in merge or replace mode within one transaction
+func (s *SQLiteStorage) ImportBackup(backup *models.Backup, mode string) error {
+	if mode != models.RestoreModeMerge && mode != models.RestoreModeReplace {
+		return fmt.Errorf("invalid restore mode: %s", mode)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 任一数据无效时不导入任何数据
+		if err := backup.Validate(); err != nil {
+			return err
+		}
+		if err := backup.ValidateData(); err != nil {
+			return fmt.Errorf("invalid backup: %w", err)
+		}
+
+		if mode == models.RestoreModeReplace {
+			for _, table := range []string{
+				"node_group_nodes",
+				"nodes",
+				"node_groups",
+				"policy_groups",
+				"rules",
+				"rule_sets",
+				"dns_rules",
+				"dns_servers",
+				"subscriptions",
+			} {
+				if err := tx.Exec("DELETE FROM " + table).Error; err != nil {
+					return fmt.Errorf("clear %s: %w", table, err)
+				}
+			}
+		}
+
+		// 按 ID 覆盖或新增
+		tables := []struct {
+			name  string
+			rows  interface{}
+			count int
+		}{
+			{"nodes", &backup.Nodes, len(backup.Nodes)},
+			{"node groups", &backup.NodeGroups, len(backup.NodeGroups)},
+			{"policy groups", &backup.PolicyGroups, len(backup.PolicyGroups)},
+			{"rules", &backup.Rules, len(backup.Rules)},
+			{"rule sets", &backup.RuleSets, len(backup.RuleSets)},
+			{"dns rules", &backup.DNSRules, len(backup.DNSRules)},
+			{"dns servers", &backup.DNSServers, len(backup.DNSServers)},
+			{"subscriptions", &backup.Subscriptions, len(backup.Subscriptions)},
+		}
+		for _, table := range tables {
+			if table.count == 0 {
+				continue
+			}
+			if err := tx.Omit(clause.Associations).Save(table.rows).Error; err != nil {
+				return fmt.Errorf("import %s: %w", table.name, err)
+			}
+		}
+
+		// 节点组成员由匹配规则决定，导入后重新匹配
+		if err := rematchNodeGroups(tx); err != nil {
+			return fmt.Errorf("match node groups: %w", err)
+		}
+
+		// 旧版本的备份可能引用已移除的"自动"出站
+		if err := migrateAutoNodeGroupOutbounds(tx, s.logger); err != nil {
+			return fmt.Errorf("migrate node group outbounds: %w", err)
+		}
+
+		// 合并后策略组标签不能与节点组重名
+		if err := checkPolicyGroupTags(tx); err != nil {
+			return fmt.Errorf("invalid backup: %w", err)
+		}
+
+		// 设置只有一条记录，两种方式都直接替换
+		if backup.Settings != nil {
+			if err := tx.Exec("DELETE FROM settings").Error; err != nil {
+				return err
+			}
+			if err := tx.Create(backup.Settings).Error; err != nil {
+				return fmt.Errorf("import settings: %w", err)
+			}
+		}
+		if backup.DNSSettings != nil {
+			if backup.DNSSettings.ID == "" {
+				backup.DNSSettings.ID = "default"
+			}
+			if err := tx.Exec("DELETE FROM dns_settings").Error; err != nil {
+				return err
+			}
+			if err := tx.Create(backup.DNSSettings).Error; err != nil {
+				return fmt.Errorf("import dns settings: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// checkPolicyGroupTags 检查策略组标签是否与内置出站、节点组或其他策略组重名
+func checkPolicyGroupTags(tx *gorm.DB) error {
+	var policyGroups []models.PolicyGroup
+	if err := tx.Find(&policyGroups).Error; err != nil {
+		return err
+	}
+	var nodeGroups []models.NodeGroup
+	if err := tx.Find(&nodeGroups).Error; err != nil {
+		return err
+	}
+
+	used := map[string]string{
+		models.PolicyOutboundProxy: "built-in outbound",
+		"direct-out":               "built-in outbound",
+		"block":                    "built-in outbound",
+		"dns-out":                  "built-in outbound",
+	}
+	for _, group := range nodeGroups {
+		used[group.Name] = "node group " + group.Name
+	}
+	for _, group := range policyGroups {
+		if other, ok := used[group.Tag()]; ok {
+			return fmt.Errorf("policy group %s conflicts with %s", group.Name, other)
+		}
+		used[group.Tag()] = "policy group " + group.Name
+	}
+	return nil
+}
+
+// rematchNodeGroups 根据节点组的匹配规则重建所有节点组成员
+func rematchNodeGroups(tx *gorm.DB) error {
+	var nodes []models.Node
+	if err := tx.Find(&nodes).Error; err != nil {
+		return err
+	}
+	var groups []models.NodeGroup
+	if err := tx.Find(&groups).Error; err != nil {
+		return err
+	}
+
+	for i := range groups {
+		matched := make([]models.Node, 0)
+		for _, node := range nodes {
+			if groups[i].Contains(&node) {
+				matched = append(matched, node)
+			}
+		}
+		if err := tx.Model(&groups[i]).Association("Nodes").Replace(&matched); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetDNSSettings returns DNS settings
 func (s *SQLiteStorage) GetDNSSettings() (*models.DNSSettings, error) {
 	var settings models.DNSSettings
@@ -1196,6 +1606,10 @@ func (s *SQLiteStorage) GetDNSSettings() (*models.DNSSettings, error) {
 
 // SaveDNSSettings saves DNS settings
 func (s *SQLiteStorage) SaveDNSSettings(settings *models.DNSSettings) error {
+	// DNS 设置只有一条记录
+	if settings.ID == "" {
+		settings.ID = "default"
+	}
 	return s.db.Save(settings).Error
 }
 
@@ -1256,7 +1670,7 @@ func createDefaultSettings(db *gorm.DB) error {
 		UpdateInterval:   24,
 		ProxyPort:        1080,
 		APIPort:          8080,
-		DNSPort:          53,
+		DNSPort:          models.DefaultDNSPort,
 		LogLevel:         "info",
 		EnableAutoUpdate: true,
 		UpdatedAt:        time.Now().Unix(),
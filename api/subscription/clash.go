@@ -31,8 +31,14 @@ type ClashProxy struct {
 	GRPC           map[string]interface{} `yaml:"grpc,omitempty"`
 	TLS            bool                   `yaml:"tls,omitempty"`
 	SNI            string                 `yaml:"sni,omitempty"`
+	ServerName     string                 `yaml:"servername,omitempty"`
+	Fingerprint    string                 `yaml:"client-fingerprint,omitempty"`
+	RealityOpts    map[string]string      `yaml:"reality-opts,omitempty"`
+	Flow           string                 `yaml:"flow,omitempty"`
 	SkipCertVerify bool                   `yaml:"skip-cert-verify,omitempty"`
 	UDP            bool                   `yaml:"udp,omitempty"`
+	Plugin         string                 `yaml:"plugin,omitempty"`
+	PluginOpts     map[string]interface{} `yaml:"plugin-opts,omitempty"`
 }
 
 // ParseClash parses Clash subscription content
@@ -107,6 +113,8 @@ func convertClashProxy(proxy *ClashProxy) (*models.Node, error) {
 		Host:           proxy.SNI,
 		SkipCertVerify: proxy.SkipCertVerify,
 		UDP:            proxy.UDP,
+		SNI:            proxy.ServerName, // vmess 和 vless 使用 servername 指定 SNI
+		Fingerprint:    proxy.Fingerprint,
 	}
 
 	proxyType := strings.ToLower(proxy.Type)
@@ -120,7 +128,19 @@ func convertClashProxy(proxy *ClashProxy) (*models.Node, error) {
 		}
 		node.Method = proxy.Cipher
 		node.Password = proxy.Password
-		logger.LogDebug("Converted Shadowsocks proxy - method: %s", node.Method)
+		if proxy.Plugin != "" {
+			opts := make(map[string]string, len(proxy.PluginOpts))
+			for key, value := range proxy.PluginOpts {
+				opts[key] = fmt.Sprint(value)
+			}
+			plugin, pluginOpts, err := models.NormalizeSSPlugin(proxy.Plugin, opts)
+			if err != nil {
+				return nil, err
+			}
+			node.Plugin = plugin
+			node.PluginOpts = pluginOpts
+		}
+		logger.LogDebug("Converted Shadowsocks proxy - method: %s, plugin: %s", node.Method, node.Plugin)
 
 	case "vmess":
 		if proxy.UUID == "" {
@@ -137,9 +157,9 @@ func convertClashProxy(proxy *ClashProxy) (*models.Node, error) {
 			logger.LogDebug("Configured WebSocket - path: %s, host: %s", node.Path, node.Host)
 		} else if proxy.Network == "grpc" && proxy.GRPC != nil {
 			if serviceName, ok := proxy.GRPC["serviceName"].(string); ok {
-				node.Path = serviceName
+				node.ServiceName = serviceName
 			}
-			logger.LogDebug("Configured gRPC - service name: %s", node.Path)
+			logger.LogDebug("Configured gRPC - service name: %s", node.ServiceName)
 		}
 		logger.LogDebug("Converted VMess proxy - uuid: %s, network: %s", node.UUID, node.Network)
 
@@ -157,6 +177,16 @@ func convertClashProxy(proxy *ClashProxy) (*models.Node, error) {
 		}
 		node.UUID = proxy.UUID
 		node.Network = proxy.Network
+		node.Flow = proxy.Flow
+		if proxy.RealityOpts != nil {
+			node.TLS = true
+			node.Security = "reality"
+			node.PublicKey = proxy.RealityOpts["public-key"]
+			node.ShortID = proxy.RealityOpts["short-id"]
+			if node.PublicKey == "" {
+				return nil, fmt.Errorf("missing required vless field - reality-opts.public-key")
+			}
+		}
 		if proxy.Network == "ws" {
 			node.Path = proxy.WSPath
 			if proxy.WSHeaders != nil {
@@ -165,9 +195,9 @@ func convertClashProxy(proxy *ClashProxy) (*models.Node, error) {
 			logger.LogDebug("Configured WebSocket - path: %s, host: %s", node.Path, node.Host)
 		} else if proxy.Network == "grpc" && proxy.GRPC != nil {
 			if serviceName, ok := proxy.GRPC["serviceName"].(string); ok {
-				node.Path = serviceName
+				node.ServiceName = serviceName
 			}
-			logger.LogDebug("Configured gRPC - service name: %s", node.Path)
+			logger.LogDebug("Configured gRPC - service name: %s", node.ServiceName)
 		}
 		logger.LogDebug("Converted VLESS proxy - uuid: %s, network: %s", node.UUID, node.Network)
 
@@ -75,6 +75,7 @@ func parseHysteria2URL(urlStr string) (*models.Node, error) {
 	// Handle obfs settings
 	if obfs := query.Get("obfs"); obfs != "" {
 		node.Obfs = obfs
+		node.ObfsPassword = query.Get("obfs-password")
 	}
 
 	// Handle other parameters
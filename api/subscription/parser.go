@@ -93,7 +93,7 @@ func ParseSubscription(content []byte, subType string) ([]*models.Node, error) {
 				node, err = parseShadowsocksURL(line)
 			case strings.HasPrefix(line, "trojan://"):
 				node, err = parseTrojanURL(line)
-			case strings.HasPrefix(line, "hy2://"):
+			case strings.HasPrefix(line, "hy2://"), strings.HasPrefix(line, "hysteria2://"):
 				node, err = parseHysteria2URL(line)
 			case strings.HasPrefix(line, "tuic://"):
 				node, err = parseTUICURL(line)
@@ -79,6 +79,16 @@ func parseShadowsocksURL(urlStr string) (*models.Node, error) {
 		Password: methodAndPassword[1],
 	}
 
+	// SIP002 插件参数，如 plugin=obfs-local;obfs=http;obfs-host=example.com
+	if plugin := uri.Query().Get("plugin"); plugin != "" {
+		name, opts, err := models.NormalizeSSPluginString(plugin, "")
+		if err != nil {
+			return nil, err
+		}
+		node.Plugin = name
+		node.PluginOpts = opts
+	}
+
 	// 如果名称为空，生成一个
 	if node.Name == "" {
 		node.Name = fmt.Sprintf("SS-%s:%d", node.Address, node.Port)
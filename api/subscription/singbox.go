@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"singdns/api/logger"
 	"singdns/api/models"
+	"strings"
 )
 
 // parseSingboxSubscription parses a SingBox subscription
@@ -28,6 +29,21 @@ func parseSingboxSubscription(content []byte) ([]*models.Node, error) {
 				Insecure    bool     `json:"insecure"`
 				ALPN        []string `json:"alpn"`
 				Fingerprint string   `json:"fingerprint"`
+				MinVersion  string   `json:"min_version"`
+				MaxVersion  string   `json:"max_version"`
+				UTLS        struct {
+					Enabled     bool   `json:"enabled"`
+					Fingerprint string `json:"fingerprint"`
+				} `json:"utls"`
+				Reality struct {
+					Enabled   bool   `json:"enabled"`
+					PublicKey string `json:"public_key"`
+					ShortID   string `json:"short_id"`
+				} `json:"reality"`
+				ECH struct {
+					Enabled bool     `json:"enabled"`
+					Config  []string `json:"config"`
+				} `json:"ech"`
 			} `json:"tls"`
 			Transport struct {
 				Type        string            `json:"type"`
@@ -36,7 +52,9 @@ func parseSingboxSubscription(content []byte) ([]*models.Node, error) {
 				ServiceName string            `json:"service_name"`
 				Headers     map[string]string `json:"headers"`
 			} `json:"transport"`
-			Flow string `json:"flow"`
+			Flow       string `json:"flow"`
+			Plugin     string `json:"plugin"`
+			PluginOpts string `json:"plugin_opts"`
 		} `json:"outbounds"`
 	}
 
@@ -64,6 +82,17 @@ func parseSingboxSubscription(content []byte) ([]*models.Node, error) {
 				Flow:     outbound.Flow,
 			}
 
+			// 处理 Shadowsocks 插件
+			if outbound.Plugin != "" {
+				plugin, pluginOpts, err := models.NormalizeSSPluginString(outbound.Plugin, outbound.PluginOpts)
+				if err != nil {
+					logger.LogWarning("跳过出站 %s: %v", outbound.Tag, err)
+					continue
+				}
+				node.Plugin = plugin
+				node.PluginOpts = pluginOpts
+			}
+
 			// 处理 TLS 设置
 			if outbound.TLS.Enabled {
 				node.TLS = true
@@ -71,6 +100,20 @@ func parseSingboxSubscription(content []byte) ([]*models.Node, error) {
 				node.SkipCertVerify = outbound.TLS.Insecure
 				node.ALPN = outbound.TLS.ALPN
 				node.Fingerprint = outbound.TLS.Fingerprint
+				node.TLSMinVersion = outbound.TLS.MinVersion
+				node.TLSMaxVersion = outbound.TLS.MaxVersion
+				if outbound.TLS.UTLS.Enabled {
+					node.Fingerprint = outbound.TLS.UTLS.Fingerprint
+				}
+				if outbound.TLS.Reality.Enabled {
+					node.Security = "reality"
+					node.PublicKey = outbound.TLS.Reality.PublicKey
+					node.ShortID = outbound.TLS.Reality.ShortID
+				}
+				if outbound.TLS.ECH.Enabled {
+					node.ECH = true
+					node.ECHConfig = strings.Join(outbound.TLS.ECH.Config, "\n")
+				}
 			}
 
 			// 处理传输层设置
@@ -48,11 +48,23 @@ func parseTrojanURL(urlStr string) (*models.Node, error) {
 
 	// Handle SNI
 	if sni := query.Get("sni"); sni != "" {
-		node.Host = sni
+		node.SNI = sni
 		logger.LogDebug("使用 SNI: %s", sni)
 	} else {
-		node.Host = uri.Hostname() // Use hostname as SNI if not specified
-		logger.LogDebug("使用主机名作为 SNI: %s", node.Host)
+		node.SNI = uri.Hostname() // Use hostname as SNI if not specified
+		logger.LogDebug("使用主机名作为 SNI: %s", node.SNI)
+	}
+
+	// Handle transport settings
+	if network := query.Get("type"); network != "" {
+		node.Network = network
+	}
+	switch node.Network {
+	case "ws", "httpupgrade", "http", "h2":
+		node.Path = query.Get("path")
+		node.Host = query.Get("host")
+	case "grpc":
+		node.ServiceName = query.Get("serviceName")
 	}
 
 	// Handle name
@@ -74,6 +86,9 @@ func parseTrojanURL(urlStr string) (*models.Node, error) {
 	if allowInsecure := query.Get("allowInsecure"); allowInsecure == "1" {
 		node.SkipCertVerify = true
 	}
+	if fp := query.Get("fp"); fp != "" {
+		node.Fingerprint = fp
+	}
 
 	logger.LogInfo("成功解析 Trojan 节点: %s (%s:%d)", node.Name, node.Address, node.Port)
 	return node, nil
@@ -90,6 +90,9 @@ func parseTUICURL(urlStr string) (*models.Node, error) {
 	if udp := query.Get("udp"); udp == "1" {
 		node.UDP = true
 	}
+	if mode := query.Get("udp_relay_mode"); mode != "" {
+		node.UDPRelayMode = mode
+	}
 
 	logger.LogInfo("成功解析 TUIC 节点: %s (%s:%d)", node.Name, node.Address, node.Port)
 	return node, nil
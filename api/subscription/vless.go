@@ -76,7 +76,7 @@ func parseVLESSURL(vlessURL string) (*models.Node, error) {
 	}
 
 	// Handle TLS settings
-	if node.Security == "tls" {
+	if node.Security == "tls" || node.Security == "reality" {
 		node.TLS = true
 		if sni := query.Get("sni"); sni != "" {
 			node.SNI = sni
@@ -89,9 +89,19 @@ func parseVLESSURL(vlessURL string) (*models.Node, error) {
 		}
 	}
 
+	// Handle REALITY settings
+	if node.Security == "reality" {
+		node.PublicKey = query.Get("pbk")
+		node.ShortID = query.Get("sid")
+		node.SpiderX = query.Get("spx")
+		if node.PublicKey == "" {
+			return nil, fmt.Errorf("missing public key for REALITY")
+		}
+	}
+
 	// Handle transport settings
 	switch node.Network {
-	case "ws":
+	case "ws", "httpupgrade", "http", "h2":
 		if path := query.Get("path"); path != "" {
 			node.Path = path
 		}
@@ -97,6 +97,12 @@ func parseVMessURL(urlStr string) (*models.Node, error) {
 	if security, ok := rawConfig["security"].(string); ok {
 		node.Security = security
 	}
+	if sni, ok := rawConfig["sni"].(string); ok {
+		node.SNI = sni
+	}
+	if fp, ok := rawConfig["fp"].(string); ok {
+		node.Fingerprint = fp
+	}
 
 	// 验证必要字段
 	if node.Address == "" || node.Port == 0 || node.UUID == "" {
@@ -50,9 +50,9 @@ func generateConfig(configType string) error {
 			Language:       "zh-CN",
 			UpdateInterval: 24,
 
-			ProxyPort:        7890,
+			ProxyPort:        models.DefaultProxyPort,
 			APIPort:          8080,
-			DNSPort:          53,
+			DNSPort:          models.DefaultDNSPort,
 			LogLevel:         "info",
 			EnableAutoUpdate: true,
 			UpdatedAt:        time.Now().Unix(),
@@ -82,19 +82,18 @@ func generateConfig(configType string) error {
 		return err
 	}
 
-	// 确保配置目录存在
-	configDir := filepath.Join("configs", "sing-box")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return fmt.Errorf("create config directory: %w", err)
-	}
-
-	// 写入配置文件
-	configPath := filepath.Join(configDir, "config.json")
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
-		return fmt.Errorf("write config file: %w", err)
+	// 通过 sing-box check 后写入配置文件并记录版本
+	configPath := filepath.Join("configs", "sing-box", "config.json")
+	history := config.NewHistory(db, configPath, config.DefaultBinPath)
+	version, err := history.Apply(data, models.ConfigTriggerManual, "")
+	if err != nil {
+		return err
 	}
 
 	fmt.Printf("Config file generated: %s\n", configPath)
+	if !version.Checked {
+		fmt.Printf("Warning: %s not found, config was not checked\n", config.DefaultBinPath)
+	}
 	return nil
 }
 
@@ -237,7 +236,7 @@ func main() {
 	}
 
 	// 初始化代理管理器
-	proxyManager := proxy.NewManager(logger, "configs/sing-box/config.json", ".")
+	proxyManager := proxy.NewManager(logger, "configs/sing-box/config.json", ".", db)
 
 	// 初始化认证管理器
 	authManager := auth.NewManager([]byte(jwtSecret), db)
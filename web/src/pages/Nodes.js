@@ -889,7 +889,14 @@ const Nodes = ({ mode, setMode, onDashboardClick }) => {
 
   // 修复中文编码题
   const renderGroupModeHelperText = (mode) => {
-    return mode === 'select' ? '手动选择节点' : '自动选择可用和延迟较低的节点';
+    switch (mode) {
+      case 'urltest':
+        return '自动选择可用和延迟较低的节点';
+      case 'fallback':
+        return '自动选择可用节点，当前节点不可用时才切换';
+      default:
+        return '手动选择节点';
+    }
   };
 
   // 渲染分组卡片
@@ -1348,6 +1355,7 @@ const Nodes = ({ mode, setMode, onDashboardClick }) => {
                   >
                     <MenuItem value="select">手动选择</MenuItem>
                     <MenuItem value="urltest">自动测速</MenuItem>
+                    <MenuItem value="fallback">故障转移</MenuItem>
                   </Select>
                   <FormHelperText>
                     {renderGroupModeHelperText(editGroup.mode)}
@@ -35,7 +35,7 @@ import {
   Dashboard as DashboardIcon,
 } from '@mui/icons-material';
 import { getCommonStyles } from '../styles/commonStyles';
-import { getSettings, updateSettings, updatePassword, updateDNSSettings, generateConfig } from '../services/api';
+import { getSettings, updateSettings, updatePassword, updateDNSSettings, generateConfig, getConfigVersions, rollbackConfigVersion } from '../services/api';
 import axios from 'axios';
 import config from '../config';
 
@@ -139,6 +139,8 @@ const Settings = ({ mode, setMode, onDashboardClick }) => {
     current_password: '',
   });
   const [inboundMode, setInboundMode] = useState('tun');
+  const [configVersions, setConfigVersions] = useState([]);
+  const [rollbackNotice, setRollbackNotice] = useState('');
 
   // 处理 DNS 设置更改
   const handleDnsSettingsChange = (field, value) => {
@@ -203,6 +205,9 @@ const Settings = ({ mode, setMode, onDashboardClick }) => {
           current_password: settings.dashboard.current_password || '',
         });
       }
+
+      const versions = await getConfigVersions();
+      setConfigVersions(Array.isArray(versions) ? versions : []);
     } catch (err) {
       console.error('Failed to load settings:', err);
       setError(err.response?.data?.error || '加载设置失败');
@@ -627,6 +632,26 @@ const Settings = ({ mode, setMode, onDashboardClick }) => {
     }
   };
 
+  // 回滚配置版本，只改写配置文件，数据不回滚
+  const handleRollback = async (version) => {
+    if (!window.confirm('回滚只替换当前配置文件，节点、规则等数据不会回滚。下次修改设置或自动更新订阅、规则集时，配置会按当前数据重新生成，回滚将失效。确定回滚吗？')) {
+      return;
+    }
+    try {
+      setLoading(true);
+      const result = await rollbackConfigVersion(version.id);
+      setRollbackNotice(result?.message || '');
+      setConfigVersions(await getConfigVersions());
+      setSuccess(true);
+    } catch (err) {
+      console.error('Failed to rollback config:', err);
+      const errorMessage = err.response?.data?.error || err.response?.data?.message || err.message;
+      setError(`回滚配置失败: ${errorMessage}`);
+    } finally {
+      setLoading(false);
+    }
+  };
+
   // 添加文件输入引用
   const fileInputRef = useRef(null);
 
@@ -1175,7 +1200,7 @@ const Settings = ({ mode, setMode, onDashboardClick }) => {
                     />
                   </Stack>
                   <Typography variant="body2" color="text.secondary" sx={{ fontSize: '0.7rem', mt: 0.5 }}>
-                    用于告知 DNS 服务器客户端的位置，以获得更准确的解析结果
+                    用于告知 DNS 服务器客户端的位置，以获得更准确的解析结果；启用 FakeIP 时，返回 FakeIP 的 A/AAAA 查询不使用此设置
                   </Typography>
                 </Box>
                 <Box sx={styles.buttonContainer}>
@@ -1193,6 +1218,51 @@ const Settings = ({ mode, setMode, onDashboardClick }) => {
             </CardContent>
           </Card>
         </Grid>
+
+        {/* 配置版本卡片 */}
+        <Grid item xs={12}>
+          <Card sx={styles.card}>
+            <CardContent sx={styles.cardContent}>
+              <Typography variant="h6" sx={{ fontSize: '0.9rem', mb: 1.5 }}>
+                配置版本
+              </Typography>
+              <Alert severity="warning" sx={{ borderRadius: '12px', mb: 1.5, fontSize: '0.8rem' }}>
+                回滚是临时的：只替换当前配置文件，节点、规则等数据不会回滚。下次修改设置或自动更新订阅、规则集时，配置会按当前数据重新生成，回滚将失效。
+              </Alert>
+              {rollbackNotice && (
+                <Alert severity="info" onClose={() => setRollbackNotice('')} sx={{ borderRadius: '12px', mb: 1.5, fontSize: '0.8rem' }}>
+                  {rollbackNotice}
+                </Alert>
+              )}
+              <Stack spacing={1}>
+                {configVersions.length === 0 && (
+                  <Typography variant="body2" color="text.secondary" sx={{ fontSize: '0.8rem' }}>
+                    暂无配置版本
+                  </Typography>
+                )}
+                {configVersions.map((version, index) => (
+                  <Stack key={version.id} direction="row" alignItems="center" spacing={2}>
+                    <Typography variant="body2" sx={{ fontSize: '0.8rem', minWidth: 160 }}>
+                      {new Date(version.created_at).toLocaleString()}
+                    </Typography>
+                    <Typography variant="body2" color="text.secondary" sx={{ fontSize: '0.8rem', flex: 1 }}>
+                      {version.trigger}{version.user ? ` · ${version.user}` : ''}{version.checked ? '' : ' · 未经 sing-box 检查'}
+                    </Typography>
+                    <Button
+                      size="small"
+                      variant="outlined"
+                      onClick={() => handleRollback(version)}
+                      disabled={loading || index === 0}
+                      sx={{ fontSize: '0.75rem' }}
+                    >
+                      {index === 0 ? '当前' : '回滚'}
+                    </Button>
+                  </Stack>
+                ))}
+              </Stack>
+            </CardContent>
+          </Card>
+        </Grid>
       </Grid>
 
       {/* 修改密码对话框 */}
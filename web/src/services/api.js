@@ -255,4 +255,14 @@ export const generateConfig = async () => {
   const response = await axios.post('/api/config/generate');
   return response.data;
 };
- 
\ No newline at end of file
+ 
+// 配置版本
+export const getConfigVersions = async () => {
+  const response = await axios.get('/api/config/versions');
+  return response.data;
+};
+
+export const rollbackConfigVersion = async (id) => {
+  const response = await axios.post(`/api/config/versions/${id}/rollback`);
+  return response.data;
+};